@@ -1,6 +1,8 @@
 package ML
 
 import (
+	"errors"
+	"fmt"
 	"io"
 )
 
@@ -10,6 +12,12 @@ type ErrorAccumulator interface {
 	Count() int
 	Estimate() float64
 	Clone() ErrorAccumulator
+	// Merge() adds everything accumulated by another accumulator
+	// of the same type and configuration, as if its inputs had
+	// been added directly, so that statistics gathered in shards
+	// can be combined.  It panics if the accumulators are
+	// incompatible.
+	Merge(ErrorAccumulator)
 	Dump(io.Writer, int)
 }
 
@@ -26,6 +34,8 @@ type WeightedErrorAccumulator interface {
 	WeightedCount() float64
 	Estimate() float64
 	Clone() WeightedErrorAccumulator
+	// Merge() is like ErrorAccumulator.Merge().
+	Merge(WeightedErrorAccumulator)
 	Dump(io.Writer, int)
 }
 
@@ -34,3 +44,128 @@ type WeightedCVAccumulator interface {
 	Remove (cateogry, weight float64)
 	Metric() float64
 }
+
+// unitWeightAccumulator adapts a CVAccumulator to the
+// WeightedCVAccumulator interface so that trees built with
+// unweighted accumulators share the weighted growth code.  Record
+// weights are ignored; every record counts once.
+type unitWeightAccumulator struct {
+	CVAccumulator
+}
+
+func unitWeightFactory(accumulatorFactory func() CVAccumulator) func() WeightedCVAccumulator {
+	return func() WeightedCVAccumulator {
+		return &unitWeightAccumulator{accumulatorFactory()}
+	}
+}
+
+func (ua *unitWeightAccumulator) Add(x, weight float64) {
+	ua.CVAccumulator.Add(x)
+}
+
+func (ua *unitWeightAccumulator) Remove(x, weight float64) {
+	ua.CVAccumulator.Remove(x)
+}
+
+func (ua *unitWeightAccumulator) WeightedCount() float64 {
+	return float64(ua.CVAccumulator.Count())
+}
+
+func (ua *unitWeightAccumulator) Clone() WeightedErrorAccumulator {
+	return &unitWeightAccumulator{ua.CVAccumulator.Clone().(CVAccumulator)}
+}
+
+func (ua *unitWeightAccumulator) Merge(other WeightedErrorAccumulator) {
+	o,ok := other.(*unitWeightAccumulator)
+	if !ok {
+		panic(errors.New(fmt.Sprintf("Cannot merge %T into an adapted %T", other, ua.CVAccumulator)))
+	}
+	ua.CVAccumulator.Merge(o.CVAccumulator)
+}
+
+// weightedAccumulatorView presents a WeightedCVAccumulator through the
+// CVAccumulator interface, adding and removing with unit weight.
+type weightedAccumulatorView struct {
+	WeightedCVAccumulator
+}
+
+func (wv *weightedAccumulatorView) Add(x float64) {
+	wv.WeightedCVAccumulator.Add(x, 1.0)
+}
+
+func (wv *weightedAccumulatorView) Remove(x float64) {
+	wv.WeightedCVAccumulator.Remove(x, 1.0)
+}
+
+func (wv *weightedAccumulatorView) Clone() ErrorAccumulator {
+	return &weightedAccumulatorView{wv.WeightedCVAccumulator.Clone().(WeightedCVAccumulator)}
+}
+
+func (wv *weightedAccumulatorView) Merge(other ErrorAccumulator) {
+	o,ok := other.(*weightedAccumulatorView)
+	if !ok {
+		panic(errors.New(fmt.Sprintf("Cannot merge %T into a view of %T", other, wv.WeightedCVAccumulator)))
+	}
+	wv.WeightedCVAccumulator.Merge(o.WeightedCVAccumulator)
+}
+
+// asCVAccumulator() returns "a" as a CVAccumulator, unwrapping it if
+// it is an adapted CVAccumulator.
+func asCVAccumulator(a WeightedCVAccumulator) CVAccumulator {
+	if ua,ok := a.(*unitWeightAccumulator); ok {
+		return ua.CVAccumulator
+	}
+	return &weightedAccumulatorView{a}
+}
+
+// categoricalAccumulator is implemented by accumulators of
+// categorical outputs.  distribution() returns the (weighted) count
+// of each category.
+type categoricalAccumulator interface {
+	categories() int
+	distribution() []float64
+}
+
+func (ea *EntropyAccumulator) categories() int {
+	return len(ea.counts)
+}
+
+func (ea *EntropyAccumulator) distribution() []float64 {
+	result := make([]float64, len(ea.counts))
+	for i,c := range ea.counts {
+		result[i] = float64(c)
+	}
+	return result
+}
+
+func (ea *WeightedEntropyAccumulator) categories() int {
+	return len(ea.weights)
+}
+
+func (ea *WeightedEntropyAccumulator) distribution() []float64 {
+	result := make([]float64, len(ea.weights))
+	copy(result, ea.weights)
+	return result
+}
+
+// innerAccumulator() returns the accumulator wrapped by the weight
+// adapters, if any.
+func innerAccumulator(a interface{}) interface{} {
+	inner := a
+	if wv,ok := a.(*weightedAccumulatorView); ok {
+		inner = wv.WeightedCVAccumulator
+	}
+	if ua,ok := inner.(*unitWeightAccumulator); ok {
+		inner = ua.CVAccumulator
+	}
+	return inner
+}
+
+// outputCategories() returns the number of output categories
+// accumulated by "a", or 1 if "a" accumulates a continuous output.
+func outputCategories(a ErrorAccumulator) int {
+	if ca,ok := innerAccumulator(a).(categoricalAccumulator); ok {
+		return ca.categories()
+	}
+	return 1
+}
@@ -0,0 +1,83 @@
+package ML
+
+import (
+	"testing"
+)
+
+func TestMerge (t *testing.T) {
+	outputs := []float64{2, 0, 1, 1, 2, 2, 0, 1, 2, 4}
+	factories := map[string]func() CVAccumulator{
+		"stat": StatAccumulatorFactory(),
+		"entropy": EntropyAccumulatorFactory(5),
+		"gini": GiniAccumulatorFactory(5),
+		"misclassification": MisclassificationAccumulatorFactory(5),
+		"poisson": PoissonAccumulatorFactory(),
+		"mae": MAEAccumulatorFactory(),
+		"quantile": QuantileAccumulatorFactory()}
+
+	for name,factory := range factories {
+		all := factory()
+		shards := []CVAccumulator{factory(), factory(), factory()}
+		for i,x := range outputs {
+			all.Add(x)
+			shards[i % 3].Add(x)
+		}
+		// Removing before merging must not matter.
+		shards[0].Add(3)
+		shards[0].Remove(3)
+
+		merged := unitWeightFactory(factory)()
+		for _,shard := range shards {
+			merged.Merge(&unitWeightAccumulator{shard})
+		}
+		if merged.Count() != all.Count() || !closeTo(merged.Estimate(), all.Estimate()) || !closeTo(merged.Metric(), all.Metric()) {
+			t.Errorf ("%s: merged count %d, estimate %g, metric %g; expected %d, %g, %g", name,
+				merged.Count(), merged.Estimate(), merged.Metric(), all.Count(), all.Estimate(), all.Metric())
+		}
+		// The merged accumulator must go on working.
+		merged.Remove(2, 1.0)
+		all.Remove(2)
+		if !closeTo(merged.Metric(), all.Metric()) {
+			t.Errorf ("%s: metric after merge and removal is %g; expected %g", name, merged.Metric(), all.Metric())
+		}
+	}
+
+	all := NewWeightedEntropyAccumulator(3)
+	first, second := NewWeightedEntropyAccumulator(3), NewWeightedEntropyAccumulator(3)
+	for i,x := range outputs[:9] {
+		all.Add(x, float64(i))
+		if i < 4 {
+			first.Add(x, float64(i))
+		} else {
+			second.Add(x, float64(i))
+		}
+	}
+	view := asCVAccumulator(first)
+	view.Merge(asCVAccumulator(second))
+	if first.Count() != all.Count() || first.WeightedCount() != all.WeightedCount() || !closeTo(first.Metric(), all.Metric()) {
+		t.Errorf ("weighted entropy: merged %v; expected %v", first, all)
+	}
+
+	counted, moreCounted := &errorAccumulator{}, &errorAccumulator{}
+	for _,e := range []float64{0, 1, 0} {
+		counted.Add(e)
+	}
+	moreCounted.Add(1)
+	counted.Merge(moreCounted)
+	if counted.Count() != 4 || counted.Estimate() != 0.5 {
+		t.Errorf ("merged error rate is %g of %d; expected 0.5 of 4", counted.Estimate(), counted.Count())
+	}
+}
+
+func TestMergeMismatch (t *testing.T) {
+	for _,other := range []ErrorAccumulator{&StatAccumulator{}, NewEntropyAccumulator(2)} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf ("merging %T into a 3-category EntropyAccumulator did not panic", other)
+				}
+			}()
+			NewEntropyAccumulator(3).Merge(other)
+		}()
+	}
+}
@@ -0,0 +1,241 @@
+package ML
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// AdaBoost trains a sequence of classifiers, each on the training
+// records reweighted to emphasize those that its predecessors
+// misclassified, and combines their votes with weights that grow
+// with their accuracy.  It implements SAMME, the multiclass
+// generalization of AdaBoost.M1, to which it reduces for two
+// categories.
+//
+// The classifiers see the boosting weights as record weights, so
+// they should be weighted, such as depth-limited trees from
+// NewWeightedTree() with a WeightedEntropyAccumulatorFactory().
+// Classifiers that ignore weights can be boosted with
+// SetResampling().
+type AdaBoost struct {
+	classifierFactory func() Classifier
+	outputCategories int
+	rounds int
+	learningRate float64
+	resampling bool
+	rng *rand.Rand
+
+	classifiers []Classifier
+	// alphas[m] is the weight of the vote of classifiers[m] and
+	// roundErrors[m] is its weighted training error.
+	alphas []float64
+	roundErrors []float64
+
+	errorAccumulator ErrorAccumulator
+}
+
+// NewAdaBoost() returns an AdaBoost model for "outputCategories"
+// categories whose classifiers are created by "classifierFactory".
+func NewAdaBoost(outputCategories int, classifierFactory func() Classifier) *AdaBoost {
+	if outputCategories < 2 {
+		panic(errors.New(fmt.Sprintf("A classifier needs at least 2 categories; got %d", outputCategories)))
+	}
+	return &AdaBoost{
+		classifierFactory: classifierFactory,
+		outputCategories: outputCategories,
+		rounds: 50,
+		learningRate: 1.0,
+		rng: rand.New(rand.NewSource(rand.Int63())),
+		errorAccumulator: &errorAccumulator{}}
+}
+
+// SetRounds() sets the largest number of classifiers.  The default is
+// 50.  Training stops early if a classifier is perfect or no better
+// than chance.
+func (ab *AdaBoost) SetRounds(rounds int) {
+	ab.rounds = rounds
+}
+
+// SetLearningRate() sets the factor by which the vote weights, and so
+// the reweighting, are scaled.  The default is 1.
+func (ab *AdaBoost) SetLearningRate(rate float64) {
+	ab.learningRate = rate
+}
+
+// SetResampling() makes each classifier train on records drawn with
+// replacement in proportion to their boosting weights, rather than
+// on all records with the boosting weights as record weights.
+func (ab *AdaBoost) SetResampling(resampling bool) {
+	ab.resampling = resampling
+}
+
+// SetRand() sets the random source used for resampling and from which
+// the random sources of the classifiers are drawn.
+func (ab *AdaBoost) SetRand(rng *rand.Rand) {
+	ab.rng = rng
+}
+
+// Rounds() returns the number of classifiers of the trained model.
+func (ab *AdaBoost) Rounds() int {
+	return len(ab.classifiers)
+}
+
+// RoundErrors() returns the weighted training error of each
+// classifier, at the weights it was trained with.
+func (ab *AdaBoost) RoundErrors() []float64 {
+	return ab.roundErrors
+}
+
+// Train() fits the model to "data", replacing any previous fit.  The
+// initial boosting weights are the record weights, whose total must
+// be positive.
+func (ab *AdaBoost) Train(data []*Data) {
+	if len(data) == 0 {
+		panic(errors.New("Train() called without data"))
+	}
+	requirePositiveWeight(data, "AdaBoost.Train()")
+	K := float64(ab.outputCategories)
+	ab.classifiers = make([]Classifier, 0, ab.rounds)
+	ab.alphas = make([]float64, 0, ab.rounds)
+	ab.roundErrors = make([]float64, 0, ab.rounds)
+
+	// The classifiers are trained on shadow copies of the records
+	// that carry the boosting weights, scaled to a mean of 1, or
+	// unit weights if they are resampled.
+	weights := make([]float64, len(data))
+	shadows := make([]*Data, len(data))
+	for i,d := range data {
+		weights[i] = d.weight
+		shadow := *d
+		shadow.weight = 1.0
+		shadow.oobAccumulator = nil
+		shadows[i] = &shadow
+	}
+
+	wrong := make([]bool, len(data))
+	for round:=0; round<ab.rounds; round++ {
+		total := 0.0
+		for _,w := range weights {
+			total += w
+		}
+		for i,w := range weights {
+			weights[i] = w/total
+			if !ab.resampling {
+				shadows[i].weight = weights[i]*float64(len(data))
+			}
+		}
+
+		classifier := ab.classifierFactory()
+		if rc,ok := classifier.(randomizedClassifier); ok {
+			rc.SetRand(rand.New(rand.NewSource(ab.rng.Int63())))
+		}
+		classifier.Train(ab.trainingSet(shadows, weights))
+
+		err := 0.0
+		for i,d := range data {
+			wrong[i] = classifier.Classify(d.FeatureSelector()).Estimate() != d.output
+			if wrong[i] {
+				err += weights[i]
+			}
+		}
+		if err >= 1.0 - 1.0/K {
+			// No better than chance.  The model needs at least
+			// one classifier.
+			if round == 0 {
+				ab.add(classifier, 1.0, err)
+			}
+			break
+		}
+		// A perfect classifier gets a vote that outweighs any
+		// others, and ends training.
+		alpha := ab.learningRate*(math.Log((1.0 - err)/math.Max(err, 1.0e-10)) + math.Log(K - 1.0))
+		ab.add(classifier, alpha, err)
+		if err == 0.0 {
+			break
+		}
+
+		for i,w := range weights {
+			if wrong[i] {
+				weights[i] = w*math.Exp(alpha)
+			}
+		}
+	}
+}
+
+// trainingSet() returns the records on which to train a classifier:
+// the shadows carrying the boosting weights, or a sample of the
+// records drawn in proportion to the boosting weights "weights",
+// which sum to 1.
+func (ab *AdaBoost) trainingSet(shadows []*Data, weights []float64) []*Data {
+	if !ab.resampling {
+		return append([]*Data(nil), shadows...)
+	}
+	cumulative := make([]float64, len(weights))
+	total := 0.0
+	for i,w := range weights {
+		total += w
+		cumulative[i] = total
+	}
+	sample := make([]*Data, len(shadows))
+	for i,_ := range sample {
+		// The first record whose cumulative weight exceeds a
+		// uniform draw
+		j := binOf(cumulative, ab.rng.Float64()*total)
+		if j == len(cumulative) {
+			j -= 1
+		}
+		sample[i] = shadows[j]
+	}
+	return sample
+}
+
+func (ab *AdaBoost) add(classifier Classifier, alpha, err float64) {
+	ab.classifiers = append(ab.classifiers, classifier)
+	ab.alphas = append(ab.alphas, alpha)
+	ab.roundErrors = append(ab.roundErrors, err)
+}
+
+// Classify() returns the weighted votes of the classifiers for the
+// categories.  Its Estimate() is the category with the most votes.
+func (ab *AdaBoost) Classify(featureSelector func(int32) float64) CVAccumulator {
+	if len(ab.classifiers) == 0 {
+		panic(errors.New("AdaBoost used before Train()"))
+	}
+	votes := NewWeightedEntropyAccumulator(ab.outputCategories)
+	for m,c := range ab.classifiers {
+		votes.Add(c.Classify(featureSelector).Estimate(), ab.alphas[m])
+	}
+	return asCVAccumulator(votes)
+}
+
+// ErrorCurve() returns the fraction of "data" misclassified by the
+// model made of the first m+1 classifiers, for each m.  On the
+// training data it is the boosting error curve.
+func (ab *AdaBoost) ErrorCurve(data []*Data) []float64 {
+	curve := make([]float64, len(ab.classifiers))
+	votes := make([]*WeightedEntropyAccumulator, len(data))
+	for i,_ := range votes {
+		votes[i] = NewWeightedEntropyAccumulator(ab.outputCategories)
+	}
+	for m,c := range ab.classifiers {
+		wrongCount := 0
+		for i,d := range data {
+			votes[i].Add(c.Classify(d.FeatureSelector()).Estimate(), ab.alphas[m])
+			if votes[i].Estimate() != d.output {
+				wrongCount += 1
+			}
+		}
+		curve[m] = float64(wrongCount)/float64(len(data))
+	}
+	return curve
+}
+
+func (ab *AdaBoost) Add(error float64) {
+	ab.errorAccumulator.Add(error)
+}
+
+func (ab *AdaBoost) Estimate() float64 {
+	return ab.errorAccumulator.Estimate()
+}
@@ -0,0 +1,129 @@
+package ML
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// adaBoostTestData() returns records in the square [-1,1]^2 whose
+// category depends on both coordinates, so that no single stump
+// classifies them well.
+func adaBoostTestData(n int, rng *rand.Rand, categories int) []*Data {
+	data := make([]*Data, n)
+	for i,_ := range data {
+		x := []float64{rng.Float64()*2.0 - 1.0, rng.Float64()*2.0 - 1.0}
+		output := 0.0
+		if x[0] + x[1] > 0.0 {
+			output = 1.0
+		}
+		if categories > 2 && x[0] > 0.5 {
+			output = 2.0
+		}
+		data[i] = &Data{continuousFeatures: x, output: output, outputCategories: categories, weight: 1.0}
+	}
+	UseColumnSelectors(data)
+	return data
+}
+
+func stumpFactory(categories int) func() Classifier {
+	return func() Classifier {
+		tree := NewWeightedTree(WeightedEntropyAccumulatorFactory(categories))
+		tree.SetMaxDepth(1)
+		tree.SetFeaturesToTry(2)
+		return tree
+	}
+}
+
+func TestAdaBoost (t *testing.T) {
+	for _,categories := range []int{2, 3} {
+		rng := rand.New(rand.NewSource(1))
+		train := adaBoostTestData(400, rng, categories)
+		test := adaBoostTestData(400, rng, categories)
+
+		ab := NewAdaBoost(categories, stumpFactory(categories))
+		ab.SetRand(rand.New(rand.NewSource(2)))
+		ab.SetRounds(100)
+		ab.Train(train)
+
+		if ab.Rounds() != 100 || len(ab.RoundErrors()) != 100 {
+			t.Errorf ("%d categories: %d rounds; expected 100", categories, ab.Rounds())
+		}
+		curve := ab.ErrorCurve(train)
+		if curve[0] < 0.15 || curve[len(curve)-1] > curve[0]/3.0 {
+			t.Errorf ("%d categories: training error went from %g to %g", categories, curve[0], curve[len(curve)-1])
+		}
+		testCurve := ab.ErrorCurve(test)
+		errors := 0
+		for _,d := range test {
+			if ab.Classify(d.FeatureSelector()).Estimate() != d.output {
+				errors += 1
+			}
+		}
+		if testCurve[len(testCurve)-1] != float64(errors)/float64(len(test)) || errors > len(test)/10 {
+			t.Errorf ("%d categories: %d of %d test records misclassified; error curve ends at %g", categories,
+				errors, len(test), testCurve[len(testCurve)-1])
+		}
+	}
+}
+
+func TestAdaBoostResampling (t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	train := adaBoostTestData(400, rng, 2)
+
+	// The stumps ignore weights, so only resampling makes them differ.
+	ab := NewAdaBoost(2, func() Classifier {
+		tree := NewTree(EntropyAccumulatorFactory(2))
+		tree.SetMaxDepth(1)
+		tree.SetFeaturesToTry(2)
+		return tree
+	})
+	ab.SetRand(rand.New(rand.NewSource(4)))
+	ab.SetRounds(60)
+	ab.SetResampling(true)
+	ab.Train(train)
+	curve := ab.ErrorCurve(train)
+	if curve[len(curve)-1] > curve[0]/2.0 {
+		t.Errorf ("training error went from %g to %g", curve[0], curve[len(curve)-1])
+	}
+	for _,d := range train {
+		if d.weight != 1.0 {
+			t.Fatalf ("training changed a record weight to %g", d.weight)
+		}
+	}
+}
+
+func TestAdaBoostPerfectClassifier (t *testing.T) {
+	data := adaBoostTestData(100, rand.New(rand.NewSource(5)), 2)
+	for _,d := range data {
+		d.output = 0.0
+		if d.continuousFeatures[0] > 0.0 {
+			d.output = 1.0
+		}
+	}
+	ab := NewAdaBoost(2, stumpFactory(2))
+	ab.SetRand(rand.New(rand.NewSource(6)))
+	ab.Train(data)
+	if ab.Rounds() != 1 || ab.RoundErrors()[0] != 0.0 || ab.ErrorCurve(data)[0] != 0.0 {
+		t.Errorf ("expected training to stop after a perfect stump; %d rounds with errors %v", ab.Rounds(), ab.RoundErrors())
+	}
+}
+
+func TestAdaBoostZeroWeights (t *testing.T) {
+	data := adaBoostTestData(50, rand.New(rand.NewSource(6)), 2)
+	// Records whose weight was never set
+	for _,d := range data {
+		d.weight = 0.0
+	}
+	for _,resampling := range []bool{false, true} {
+		ab := NewAdaBoost(2, stumpFactory(2))
+		ab.SetResampling(resampling)
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf ("trained on records of zero total weight with resampling %v; alphas %v", resampling, ab.alphas)
+				}
+			}()
+			ab.Train(data)
+		}()
+	}
+}
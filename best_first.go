@@ -0,0 +1,70 @@
+package ML
+
+import (
+	"container/heap"
+)
+
+// growthCandidate is a leaf that may be split during best-first
+// growth, with its records, its remaining depth and its best split.
+type growthCandidate struct {
+	node *treeNode
+	data []*Data
+	maxDepth int
+	seed int32
+	splitInfo SplitInfo
+	// gain is the decrease in the metric of the tree from the split.
+	gain float64
+}
+
+// candidateHeap is a max-heap of growth candidates by gain.
+type candidateHeap []*growthCandidate
+
+func (h candidateHeap) Len() int {
+	return len(h)
+}
+
+func (h candidateHeap) Less(i, j int) bool {
+	return h[i].gain > h[j].gain
+}
+
+func (h candidateHeap) Swap(i, j int) {
+	h[i],h[j] = h[j],h[i]
+}
+
+func (h *candidateHeap) Push(x interface{}) {
+	*h = append(*h, x.(*growthCandidate))
+}
+
+func (h *candidateHeap) Pop() interface{} {
+	n := len(*h)
+	x := (*h)[n-1]
+	*h = (*h)[0:n-1]
+	return x
+}
+
+// growBestFirst() grows the tree from "tree" like grow(), except that
+// it always splits the leaf whose split most decreases the weighted
+// metric of the tree, and stops at config.maxLeaves leaves.
+func (tree *treeNode) growBestFirst(data []*Data, maxDepth int, config *Tree) {
+	candidates := &candidateHeap{}
+	consider := func(node *treeNode, data []*Data, maxDepth int) {
+		splitInfo, seed := node.bestSplit(data, maxDepth, config)
+		if seed != -1 {
+			heap.Push(candidates, &growthCandidate{
+				node: node,
+				data: data,
+				maxDepth: maxDepth,
+				seed: seed,
+				splitInfo: splitInfo,
+				gain: config.impurityDecrease(node, splitInfo)})
+		}
+	}
+
+	consider(tree, data, maxDepth)
+	for leaves:=1; leaves<config.maxLeaves && candidates.Len() > 0; leaves++ {
+		c := heap.Pop(candidates).(*growthCandidate)
+		leftData, rightData := c.node.split(c.data, c.seed, c.splitInfo)
+		consider(c.node.left, leftData, c.maxDepth-1)
+		consider(c.node.right, rightData, c.maxDepth-1)
+	}
+}
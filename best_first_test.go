@@ -0,0 +1,41 @@
+package ML
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestMaxLeaves (t *testing.T) {
+	rng := rand.New(rand.NewSource(10))
+	data := boostingTestData(300, rng, func(x []float64) float64 {
+		if x[0] < 0.0 {
+			return rng.NormFloat64()
+		}
+		return 20.0 + 10.0*x[1] + rng.NormFloat64()
+	})
+	newTree := func(maxLeaves int) *Tree {
+		tree := NewTree(StatAccumulatorFactory())
+		tree.SetFeaturesToTry(2)
+		tree.SetMaxLeaves(maxLeaves)
+		tree.SetRand(rand.New(rand.NewSource(11)))
+		tree.Train(append([]*Data(nil), data...))
+		return tree
+	}
+
+	full := newTree(0)
+	if unlimited := newTree(full.Leaves() + 10); unlimited.Leaves() != full.Leaves() {
+		t.Errorf ("best-first tree has %d leaves, depth-first %d", unlimited.Leaves(), full.Leaves())
+	}
+
+	// The best splits are all on the side where the output varies.
+	tree := newTree(6)
+	if tree.Leaves() != 6 {
+		t.Fatalf ("tree has %d leaves, expected 6", tree.Leaves())
+	}
+	if tree.root.seed != 0 || tree.root.splitValue < -0.1 || tree.root.splitValue > 0.1 {
+		t.Errorf ("root split on %d at %g", tree.root.seed, tree.root.splitValue)
+	}
+	if tree.root.left.seed != -1 {
+		t.Errorf ("the constant side was split")
+	}
+}
@@ -0,0 +1,484 @@
+package ML
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// A Loss is a loss function for gradient boosting of a continuous
+// output.
+type Loss interface {
+	// Loss() returns the loss of predicting "prediction" for
+	// "output".
+	Loss(output, prediction float64) float64
+	// NegativeGradient() returns the negative gradient of the loss
+	// with respect to the prediction, which is the pseudo-residual
+	// fitted by each boosted tree.
+	NegativeGradient(output, prediction float64) float64
+	// Estimate() returns the constant that minimizes the total loss
+	// of predicting it for "residuals" with the given weights.  It
+	// is the initial prediction and the value of each leaf.
+	Estimate(residuals, weights []float64) float64
+}
+
+// weightedMedian() returns the median of "values" with the given
+// weights.
+func weightedMedian(values, weights []float64) float64 {
+	distribution := make(weightedValues, len(values))
+	for i,v := range values {
+		distribution[i] = weightedValue{v, weights[i]}
+	}
+	return distribution.quantiles([]float64{0.5})[0]
+}
+
+type squaredLoss struct {}
+
+// SquaredLoss() returns the squared error loss, (y - f)^2 / 2.  Its
+// trees fit the residuals and its leaves are their mean.
+func SquaredLoss() Loss {
+	return squaredLoss{}
+}
+
+func (squaredLoss) Loss(output, prediction float64) float64 {
+	return (output - prediction)*(output - prediction)/2.0
+}
+
+func (squaredLoss) NegativeGradient(output, prediction float64) float64 {
+	return output - prediction
+}
+
+func (squaredLoss) Estimate(residuals, weights []float64) float64 {
+	var sa WeightedStatAccumulator
+	for i,r := range residuals {
+		sa.Add(r, weights[i])
+	}
+	return sa.Estimate()
+}
+
+type absoluteLoss struct {}
+
+// AbsoluteLoss() returns the absolute error loss, |y - f|.  Its trees
+// fit the signs of the residuals and its leaves are their median.
+func AbsoluteLoss() Loss {
+	return absoluteLoss{}
+}
+
+func (absoluteLoss) Loss(output, prediction float64) float64 {
+	return math.Abs(output - prediction)
+}
+
+func (absoluteLoss) NegativeGradient(output, prediction float64) float64 {
+	switch {
+	case output > prediction:
+		return 1.0
+	case output < prediction:
+		return -1.0
+	}
+	return 0.0
+}
+
+func (absoluteLoss) Estimate(residuals, weights []float64) float64 {
+	return weightedMedian(residuals, weights)
+}
+
+type huberLoss struct {
+	delta float64
+}
+
+// HuberLoss() returns the Huber loss, which is the squared error loss
+// for residuals up to "delta" in magnitude and grows linearly beyond,
+// so that outliers have limited influence.  Leaves take one step of
+// Huber M-estimation from the median of the residuals.
+func HuberLoss(delta float64) Loss {
+	if delta <= 0.0 {
+		panic(errors.New(fmt.Sprintf("Huber loss parameter %g is not positive", delta)))
+	}
+	return huberLoss{delta}
+}
+
+func (hl huberLoss) Loss(output, prediction float64) float64 {
+	r := math.Abs(output - prediction)
+	if r <= hl.delta {
+		return r*r/2.0
+	}
+	return hl.delta*(r - hl.delta/2.0)
+}
+
+func (hl huberLoss) NegativeGradient(output, prediction float64) float64 {
+	return math.Max(-hl.delta, math.Min(hl.delta, output - prediction))
+}
+
+func (hl huberLoss) Estimate(residuals, weights []float64) float64 {
+	median := weightedMedian(residuals, weights)
+	clipped := make([]float64, len(residuals))
+	for i,r := range residuals {
+		clipped[i] = hl.NegativeGradient(r, median)
+	}
+	return median + squaredLoss{}.Estimate(clipped, weights)
+}
+
+// boostedTree is one tree of a GradientBoosting model along with the
+// value that the model adds for each of its leaves.
+type boostedTree struct {
+	tree *Tree
+	values map[*treeNode]float64
+}
+
+func (bt *boostedTree) value(featureSelector func(int32) float64) float64 {
+	return bt.values[bt.tree.root.leaf(featureSelector)]
+}
+
+// GradientBoosting fits a sequence of shallow regression trees, each
+// to the negative gradient of the loss of the predictions of the
+// trees before it.  A regression model predicts the sum of the
+// initial estimate and the scaled values of the leaves of its trees.
+// A classification model fits one tree per category in each round
+// and predicts the category probabilities with the softmax of the
+// per-category sums, minimizing the multinomial log-loss.
+type GradientBoosting struct {
+	// loss is nil for classification.
+	loss Loss
+	outputCategories int
+
+	learningRate float64
+	rounds int
+	treeFactory func() *Tree
+	rng *rand.Rand
+
+	// validation is the data used for early stopping, which stops
+	// training once the validation loss has not improved for
+	// "patience" rounds.
+	validation []*Data
+	patience int
+
+	// initial[k] and trees[r][k] are the initial estimate and the
+	// round r tree of category k, or of the output for regression.
+	initial []float64
+	trees [][]*boostedTree
+	validationLoss []float64
+
+	errorAccumulator ErrorAccumulator
+}
+
+// NewGradientBoosting() returns a regression model that minimizes
+// "loss".
+func NewGradientBoosting(loss Loss) *GradientBoosting {
+	return &GradientBoosting{
+		loss: loss,
+		outputCategories: 1,
+		learningRate: 0.1,
+		rounds: 100,
+		rng: rand.New(rand.NewSource(rand.Int63())),
+		errorAccumulator: &errorAccumulator{}}
+}
+
+// NewGradientBoostingClassifier() returns a classification model for
+// "outputCategories" categories that minimizes the multinomial
+// log-loss.
+func NewGradientBoostingClassifier(outputCategories int) *GradientBoosting {
+	if outputCategories < 2 {
+		panic(errors.New(fmt.Sprintf("A classifier needs at least 2 categories; got %d", outputCategories)))
+	}
+	gb := NewGradientBoosting(nil)
+	gb.outputCategories = outputCategories
+	return gb
+}
+
+// SetLearningRate() sets the factor by which the leaf values of each
+// tree are scaled.  The default is 0.1.
+func (gb *GradientBoosting) SetLearningRate(rate float64) {
+	gb.learningRate = rate
+}
+
+// SetRounds() sets the number of boosting rounds.  The default is 100.
+func (gb *GradientBoosting) SetRounds(rounds int) {
+	gb.rounds = rounds
+}
+
+// SetTreeFactory() sets the function that creates each tree, which
+// must accumulate continuous outputs, such as
+// NewTree(StatAccumulatorFactory()).  By default trees have a
+// maximum depth of 3 and try every column at each node if the
+// records select columns (see UseColumnSelectors()).
+func (gb *GradientBoosting) SetTreeFactory(factory func() *Tree) {
+	gb.treeFactory = factory
+}
+
+// SetValidation() makes Train() stop when the mean loss on
+// "validation" has not improved for "patience" rounds.  The rounds
+// after the best one are then discarded.
+func (gb *GradientBoosting) SetValidation(validation []*Data, patience int) {
+	gb.validation = validation
+	gb.patience = patience
+}
+
+// SetRand() sets the random source from which the random sources of
+// the trees are drawn.
+func (gb *GradientBoosting) SetRand(rng *rand.Rand) {
+	gb.rng = rng
+}
+
+// Rounds() returns the number of rounds of the trained model.
+func (gb *GradientBoosting) Rounds() int {
+	return len(gb.trees)
+}
+
+// ValidationLoss() returns the mean validation loss after each round
+// of training, including any rounds discarded by early stopping.
+func (gb *GradientBoosting) ValidationLoss() []float64 {
+	return gb.validationLoss
+}
+
+func (gb *GradientBoosting) defaultTree(data []*Data) *Tree {
+	tree := NewTree(StatAccumulatorFactory())
+	tree.SetMaxDepth(3)
+	if data[0].selectsColumns {
+		tree.SetFeaturesToTry(len(data[0].continuousFeatures) + len(data[0].categoricalFeatures))
+	}
+	return tree
+}
+
+// lossOf() returns the loss of the model outputs "f" for "d".
+func (gb *GradientBoosting) lossOf(d *Data, f []float64) float64 {
+	if gb.loss != nil {
+		return gb.loss.Loss(d.output, f[0])
+	}
+	return -math.Log(math.Max(softmax(f)[int(d.output)], 1.0e-300))
+}
+
+// softmax() returns the probabilities of the categories whose model
+// outputs are "f".
+func softmax(f []float64) []float64 {
+	largest := math.Inf(-1)
+	for _,v := range f {
+		largest = math.Max(largest, v)
+	}
+	result := make([]float64, len(f))
+	total := 0.0
+	for k,v := range f {
+		result[k] = math.Exp(v - largest)
+		total += result[k]
+	}
+	for k,_ := range result {
+		result[k] /= total
+	}
+	return result
+}
+
+// Train() fits the model to "data", replacing any previous fit.
+// Record weights are taken into account by the initial estimate and
+// the leaf values, and by the trees if they are weighted.  It panics
+// if the records of "data" or of the validation set have no total
+// weight.
+func (gb *GradientBoosting) Train(data []*Data) {
+	if len(data) == 0 {
+		panic(errors.New("Train() called without data"))
+	}
+	requirePositiveWeight(data, "GradientBoosting.Train()")
+	requirePositiveWeight(gb.validation, "GradientBoosting.Train() validation")
+	K := gb.outputCategories
+	factory := gb.treeFactory
+	if factory == nil {
+		factory = func() *Tree { return gb.defaultTree(data) }
+	}
+
+	weights := make([]float64, len(data))
+	outputs := make([]float64, len(data))
+	for i,d := range data {
+		weights[i] = d.weight
+		outputs[i] = d.output
+	}
+	gb.initial = make([]float64, K)
+	if gb.loss != nil {
+		gb.initial[0] = gb.loss.Estimate(outputs, weights)
+	} else {
+		// The log of the prior probability of each category
+		prior := make([]float64, K)
+		total := 0.0
+		for i,d := range data {
+			prior[int(d.output)] += weights[i]
+			total += weights[i]
+		}
+		for k,p := range prior {
+			gb.initial[k] = math.Log(math.Max(p/total, 1.0e-300))
+		}
+	}
+
+	// f[i] holds the model outputs for data[i] and validationF[i]
+	// those for gb.validation[i].
+	newOutputs := func(n int) [][]float64 {
+		result := make([][]float64, n)
+		for i,_ := range result {
+			result[i] = make([]float64, K)
+			copy(result[i], gb.initial)
+		}
+		return result
+	}
+	f := newOutputs(len(data))
+	validationF := newOutputs(len(gb.validation))
+
+	// Each tree is trained on shadow copies of the records whose
+	// output is the pseudo-residual.
+	shadows := make([]*Data, len(data))
+	for i,d := range data {
+		shadow := *d
+		shadow.outputCategories = 1
+		shadow.oobAccumulator = nil
+		shadows[i] = &shadow
+	}
+
+	gb.trees = make([][]*boostedTree, 0, gb.rounds)
+	gb.validationLoss = make([]float64, 0, gb.rounds)
+	bestRound, bestLoss := 0, math.Inf(1)
+	probabilities := make([][]float64, len(data))
+	for round:=0; round<gb.rounds; round++ {
+		if gb.loss == nil {
+			for i,_ := range data {
+				probabilities[i] = softmax(f[i])
+			}
+		}
+		trees := make([]*boostedTree, K)
+		for k,_ := range trees {
+			for i,d := range data {
+				if gb.loss != nil {
+					shadows[i].output = gb.loss.NegativeGradient(d.output, f[i][0])
+				} else {
+					y := 0.0
+					if int(d.output) == k {
+						y = 1.0
+					}
+					shadows[i].output = y - probabilities[i][k]
+				}
+			}
+			trees[k] = gb.fitTree(factory(), shadows, data, f, k)
+		}
+		// The trees of a round are fitted to the same outputs, so
+		// the model outputs are updated after all of them.
+		for k,bt := range trees {
+			for i,d := range data {
+				f[i][k] += gb.learningRate*bt.value(d.feature)
+			}
+			for i,d := range gb.validation {
+				validationF[i][k] += gb.learningRate*bt.value(d.feature)
+			}
+		}
+		gb.trees = append(gb.trees, trees)
+
+		if len(gb.validation) == 0 {
+			continue
+		}
+		loss, total := 0.0, 0.0
+		for i,d := range gb.validation {
+			loss += d.weight*gb.lossOf(d, validationF[i])
+			total += d.weight
+		}
+		loss /= total
+		gb.validationLoss = append(gb.validationLoss, loss)
+		if loss < bestLoss {
+			bestRound, bestLoss = round+1, loss
+		} else if round+1 - bestRound >= gb.patience {
+			break
+		}
+	}
+	if len(gb.validation) > 0 {
+		gb.trees = gb.trees[0:bestRound]
+	}
+}
+
+// fitTree() trains "tree" on "shadows" and sets the value of each of
+// its leaves from the records of "data" that reach it, whose model
+// outputs are "f", for category "k".
+func (gb *GradientBoosting) fitTree(tree *Tree, shadows, data []*Data, f [][]float64, k int) *boostedTree {
+	tree.SetRand(rand.New(rand.NewSource(gb.rng.Int63())))
+	// Training reorders its data, and shadows[i] must stay the
+	// shadow of data[i].
+	tree.Train(append([]*Data(nil), shadows...))
+
+	residuals := make(map[*treeNode][]float64)
+	weights := make(map[*treeNode][]float64)
+	for i,d := range data {
+		leaf := tree.root.leaf(d.feature)
+		if gb.loss != nil {
+			residuals[leaf] = append(residuals[leaf], d.output - f[i][0])
+		} else {
+			residuals[leaf] = append(residuals[leaf], shadows[i].output)
+		}
+		weights[leaf] = append(weights[leaf], d.weight)
+	}
+
+	bt := &boostedTree{tree: tree, values: make(map[*treeNode]float64)}
+	for leaf,r := range residuals {
+		if gb.loss != nil {
+			bt.values[leaf] = gb.loss.Estimate(r, weights[leaf])
+			continue
+		}
+		// One Newton step for the multinomial log-loss
+		numerator, denominator := 0.0, 0.0
+		for i,g := range r {
+			numerator += weights[leaf][i]*g
+			denominator += weights[leaf][i]*math.Abs(g)*(1.0 - math.Abs(g))
+		}
+		K := float64(gb.outputCategories)
+		if denominator > 1.0e-12 {
+			bt.values[leaf] = (K - 1.0)/K*numerator/denominator
+		}
+	}
+	return bt
+}
+
+// outputs() returns the model outputs for a record.
+func (gb *GradientBoosting) outputs(featureSelector func(int32) float64) []float64 {
+	if gb.initial == nil {
+		panic(errors.New("GradientBoosting used before Train()"))
+	}
+	f := make([]float64, len(gb.initial))
+	copy(f, gb.initial)
+	for _,trees := range gb.trees {
+		for k,bt := range trees {
+			f[k] += gb.learningRate*bt.value(featureSelector)
+		}
+	}
+	return f
+}
+
+// Predict() returns the prediction of a regression model or the most
+// probable category of a classification model.
+func (gb *GradientBoosting) Predict(featureSelector func(int32) float64) float64 {
+	return gb.Classify(featureSelector).Estimate()
+}
+
+// Probabilities() returns the probability of each category predicted
+// by a classification model.
+func (gb *GradientBoosting) Probabilities(featureSelector func(int32) float64) []float64 {
+	if gb.loss != nil {
+		panic(errors.New("Probabilities() called on a regression model"))
+	}
+	return softmax(gb.outputs(featureSelector))
+}
+
+// Classify() returns the prediction as an accumulator, so that the
+// model can be used as a Classifier.  For regression it holds the
+// prediction alone.  For classification it holds the probability of
+// each category as its weight.
+func (gb *GradientBoosting) Classify(featureSelector func(int32) float64) CVAccumulator {
+	if gb.loss != nil {
+		result := &StatAccumulator{}
+		result.Add(gb.outputs(featureSelector)[0])
+		return result
+	}
+	result := NewWeightedEntropyAccumulator(gb.outputCategories)
+	for k,p := range gb.Probabilities(featureSelector) {
+		result.Add(float64(k), p)
+	}
+	return asCVAccumulator(result)
+}
+
+func (gb *GradientBoosting) Add(error float64) {
+	gb.errorAccumulator.Add(error)
+}
+
+func (gb *GradientBoosting) Estimate() float64 {
+	return gb.errorAccumulator.Estimate()
+}
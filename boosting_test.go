@@ -0,0 +1,194 @@
+package ML
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func boostingTestData(n int, rng *rand.Rand, output func(x []float64) float64) []*Data {
+	data := make([]*Data, n)
+	for i,_ := range data {
+		features := []float64{rng.Float64()*4.0 - 2.0, rng.Float64()*4.0 - 2.0}
+		data[i] = &Data{continuousFeatures: features, output: output(features), outputCategories: 1, weight: 1.0}
+	}
+	UseColumnSelectors(data)
+	return data
+}
+
+func meanLoss(gb *GradientBoosting, loss Loss, data []*Data) float64 {
+	total := 0.0
+	for _,d := range data {
+		total += loss.Loss(d.output, gb.Predict(d.FeatureSelector()))
+	}
+	return total/float64(len(data))
+}
+
+func TestLosses (t *testing.T) {
+	residuals := []float64{1, 2, 3, 4, 100}
+	weights := []float64{1, 1, 1, 1, 1}
+	if e := SquaredLoss().Estimate(residuals, weights); e != 22 {
+		t.Errorf ("squared loss estimate is %g; expected the mean 22", e)
+	}
+	if e := AbsoluteLoss().Estimate(residuals, weights); e != 3 {
+		t.Errorf ("absolute loss estimate is %g; expected the median 3", e)
+	}
+	// The median plus the mean of the deviations clipped to 1.5:
+	// 3 + (-1.5 - 1 + 0 + 1 + 1.5)/5
+	if e := HuberLoss(1.5).Estimate(residuals, weights); e != 3 {
+		t.Errorf ("Huber loss estimate is %g; expected 3", e)
+	}
+	if g := HuberLoss(1.5).NegativeGradient(10, 0); g != 1.5 {
+		t.Errorf ("Huber gradient is %g; expected 1.5", g)
+	}
+	if l := HuberLoss(1.5).Loss(10, 0); l != 1.5*(10 - 0.75) {
+		t.Errorf ("Huber loss is %g; expected %g", l, 1.5*(10 - 0.75))
+	}
+}
+
+func TestGradientBoostingRegression (t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	target := func(x []float64) float64 { return x[0]*x[0] + math.Sin(2.0*x[1]) }
+	train := boostingTestData(500, rng, target)
+	test := boostingTestData(200, rng, target)
+	// A few wild outputs in the training set
+	for _,d := range train[0:10] {
+		d.output += 50.0
+	}
+
+	for _,loss := range []Loss{SquaredLoss(), AbsoluteLoss(), HuberLoss(1.0)} {
+		gb := NewGradientBoosting(loss)
+		gb.SetRand(rand.New(rand.NewSource(2)))
+		gb.SetRounds(200)
+		// Robust losses need leaves with more than an outlier.
+		gb.SetTreeFactory(func() *Tree {
+			tree := NewTree(StatAccumulatorFactory())
+			tree.SetMaxDepth(3)
+			tree.SetMinLeafSize(10)
+			tree.SetFeaturesToTry(2)
+			return tree
+		})
+		gb.Train(train)
+		if gb.Rounds() != 200 {
+			t.Errorf ("%T: %d rounds; expected 200", loss, gb.Rounds())
+		}
+		mse := 2.0*meanLoss(gb, SquaredLoss(), test)
+		limit := 0.2
+		if _,ok := loss.(squaredLoss); ok {
+			// The outliers pull the squared loss model around.
+			limit = 10.0
+		}
+		if mse > limit {
+			t.Errorf ("%T: test mean squared error %g exceeds %g", loss, mse, limit)
+		}
+	}
+}
+
+func TestGradientBoostingClassification (t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	category := func(x []float64) float64 {
+		switch {
+		case x[0] + x[1] > 1.0:
+			return 2.0
+		case x[0]*x[1] > 0.0:
+			return 1.0
+		}
+		return 0.0
+	}
+	train := boostingTestData(600, rng, category)
+	test := boostingTestData(300, rng, category)
+	for _,d := range append(train, test...) {
+		d.outputCategories = 3
+	}
+
+	gb := NewGradientBoostingClassifier(3)
+	gb.SetRand(rand.New(rand.NewSource(4)))
+	gb.SetLearningRate(0.3)
+	gb.SetRounds(60)
+	gb.Train(train)
+
+	errors := 0
+	for _,d := range test {
+		p := gb.Probabilities(d.FeatureSelector())
+		if math.Abs(p[0] + p[1] + p[2] - 1.0) > 1.0e-9 {
+			t.Fatalf ("probabilities %v do not add up to 1", p)
+		}
+		if gb.Predict(d.FeatureSelector()) != d.output {
+			errors += 1
+		}
+	}
+	if errors > len(test)/10 {
+		t.Errorf ("%d of %d test records misclassified", errors, len(test))
+	}
+
+	// The model can vote in an ensemble.
+	ensemble := NewEnsemble()
+	ensemble.AddClassifier(gb)
+	if e,_ := ensemble.Predict(test[0].FeatureSelector()); e != gb.Predict(test[0].FeatureSelector()) {
+		t.Errorf ("ensemble predicts %g; expected %g", e, gb.Predict(test[0].FeatureSelector()))
+	}
+}
+
+func TestGradientBoostingEarlyStopping (t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	noisy := func(x []float64) float64 { return x[0] + 2.0*rng.NormFloat64() }
+	train := boostingTestData(200, rng, noisy)
+	validation := boostingTestData(200, rng, noisy)
+
+	gb := NewGradientBoosting(SquaredLoss())
+	gb.SetRand(rand.New(rand.NewSource(6)))
+	gb.SetLearningRate(0.5)
+	gb.SetRounds(500)
+	gb.SetValidation(validation, 10)
+	gb.Train(train)
+
+	losses := gb.ValidationLoss()
+	if len(losses) >= 500 || gb.Rounds() != len(losses) - 10 {
+		t.Fatalf ("expected training to stop 10 rounds after the best; %d rounds kept of %d", gb.Rounds(), len(losses))
+	}
+	best := losses[gb.Rounds()-1]
+	for r,l := range losses {
+		if l < best {
+			t.Errorf ("round %d has validation loss %g below that of the kept rounds, %g", r+1, l, best)
+		}
+	}
+	if math.Abs(2.0*meanLoss(gb, SquaredLoss(), validation) - 2.0*best) > 1.0e-9 {
+		t.Errorf ("validation loss of the kept rounds is %g; expected %g", meanLoss(gb, SquaredLoss(), validation), best)
+	}
+}
+
+func TestGradientBoostingZeroWeights (t *testing.T) {
+	rng := rand.New(rand.NewSource(12))
+	step := func(x []float64) float64 {
+		if x[0] < 0.0 {
+			return 0.0
+		}
+		return 1.0
+	}
+	weighted := boostingTestData(50, rng, step)
+	// Records whose weight was never set
+	unweighted := boostingTestData(50, rng, step)
+	for _,d := range unweighted {
+		d.weight = 0.0
+	}
+
+	panics := func(train func()) (result bool) {
+		defer func() {
+			result = recover() != nil
+		}()
+		train()
+		return
+	}
+
+	if !panics(func() { NewGradientBoosting(SquaredLoss()).Train(unweighted) }) {
+		t.Errorf ("regression trained on records of zero total weight")
+	}
+	if !panics(func() { NewGradientBoostingClassifier(2).Train(unweighted) }) {
+		t.Errorf ("classification trained on records of zero total weight")
+	}
+	gb := NewGradientBoosting(SquaredLoss())
+	gb.SetValidation(unweighted, 5)
+	if !panics(func() { gb.Train(weighted) }) {
+		t.Errorf ("trained with a validation set of zero total weight")
+	}
+}
@@ -0,0 +1,201 @@
+package ML
+
+import (
+	"sort"
+)
+
+// Categorical features are selected with negative seeds so that they
+// share the seed space of the continuous features: categorical
+// column i is selected by seed -2-i.  Seed -1 marks a leaf.
+
+func categoricalSeed(column int) int32 {
+	return int32(-2 - column)
+}
+
+func categoricalColumn(seed int32) int {
+	return int(-2 - seed)
+}
+
+// categorySets() returns the categories that a categorical split
+// sends left and the categories seen in training that it sends right.
+func (tree *treeNode) categorySets() (left, right []int) {
+	for c,l := range tree.leftCategories {
+		if l {
+			left = append(left, c)
+		} else {
+			right = append(right, c)
+		}
+	}
+	return left, right
+}
+
+// categoryStatistics holds the records of one category of a
+// categorical feature.
+type categoryStatistics struct {
+	category int
+	rows []*Data
+	weight float64
+	outputSum float64
+}
+
+type byMeanOutput []*categoryStatistics
+
+func (b byMeanOutput) Len() int {
+	return len(b)
+}
+
+func (b byMeanOutput) Less(i, j int) bool {
+	return b[i].outputSum/b[i].weight < b[j].outputSum/b[j].weight
+}
+
+func (b byMeanOutput) Swap(i, j int) {
+	b[i],b[j] = b[j],b[i]
+}
+
+// evaluateCategoricalSplit() returns the split that sends the
+// categories in "leftCategories" left and everything else right.
+// Missing categories go left if "missingLeft" is true.
+func evaluateCategoricalSplit(data []*Data, column int, leftCategories []bool, missingLeft bool, config *Tree) SplitInfo {
+	left := config.accumulatorFactory()
+	right := config.accumulatorFactory()
+	left.Clear()
+	right.Clear()
+	for _,row := range data {
+		c := row.categoricalFeatures[column]
+		if (c < 0 && missingLeft) || (c >= 0 && leftCategories[c]) {
+			left.Add(row.output, row.weight)
+		} else {
+			right.Add(row.output, row.weight)
+		}
+	}
+	return SplitInfo{
+		compositeSplitMetric: compositeMetric(left, right),
+		featureType: CATEGORICAL,
+		leftCategories: leftCategories,
+		missingLeft: missingLeft,
+		left: left,
+		right: right}
+}
+
+// Split the data along categorical column "column" by partitioning
+// the categories into a left and a right subset.  For continuous
+// outputs and for two output categories the categories are ordered
+// by their mean output and only splits consistent with that order
+// are tried; this is known to find the best partition.  For more
+// output categories, random partitions are tried.  Missing
+// categories go to whichever side gives the lower metric.  The
+// returned left partition is empty if the error cannot be reduced.
+func categoricalFeatureSplit(data []*Data, column int, config *Tree) SplitInfo {
+	categoryCount := 0
+	for _,row := range data {
+		if c := row.categoricalFeatures[column]; c >= categoryCount {
+			categoryCount = c + 1
+		}
+	}
+
+	byCategory := make([]*categoryStatistics, categoryCount)
+	present := make([]*categoryStatistics, 0, categoryCount)
+	hasMissing := false
+	for _,row := range data {
+		c := row.categoricalFeatures[column]
+		if c < 0 {
+			hasMissing = true
+			continue
+		}
+		if byCategory[c] == nil {
+			byCategory[c] = &categoryStatistics{category: c}
+			present = append(present, byCategory[c])
+		}
+		w := config.recordWeight(row)
+		byCategory[c].rows = append(byCategory[c].rows, row)
+		byCategory[c].weight += w
+		byCategory[c].outputSum += w*row.output
+	}
+
+	noSplit := evaluateCategoricalSplit(data, column, make([]bool, categoryCount), false, config)
+	if len(present) < 2 {
+		return noSplit
+	}
+
+	best := noSplit
+	if data[0].outputCategories <= 2 {
+		sort.Stable(byMeanOutput(present))
+
+		// leftWithMissing and rightWithMissing are the left and
+		// right partitions with the missing categories added.
+		left := config.accumulatorFactory()
+		right := config.accumulatorFactory()
+		leftWithMissing := config.accumulatorFactory()
+		rightWithMissing := config.accumulatorFactory()
+		left.Clear()
+		right.Clear()
+		leftWithMissing.Clear()
+		rightWithMissing.Clear()
+		for _,row := range data {
+			if row.categoricalFeatures[column] < 0 {
+				leftWithMissing.Add(row.output, row.weight)
+			} else {
+				right.Add(row.output, row.weight)
+			}
+			rightWithMissing.Add(row.output, row.weight)
+		}
+
+		bestSize := 0
+		bestMissingLeft := false
+		for i,cs := range present[0:len(present)-1] {
+			for _,row := range cs.rows {
+				left.Add(row.output, row.weight)
+				right.Remove(row.output, row.weight)
+				leftWithMissing.Add(row.output, row.weight)
+				rightWithMissing.Remove(row.output, row.weight)
+			}
+			if error := compositeMetric(left, rightWithMissing); error < best.compositeSplitMetric {
+				best.compositeSplitMetric = error
+				bestSize = i + 1
+				bestMissingLeft = false
+			}
+			if !hasMissing {
+				continue
+			}
+			if error := compositeMetric(leftWithMissing, right); error < best.compositeSplitMetric {
+				best.compositeSplitMetric = error
+				bestSize = i + 1
+				bestMissingLeft = true
+			}
+		}
+		if bestSize > 0 {
+			leftCategories := make([]bool, categoryCount)
+			for _,cs := range present[0:bestSize] {
+				leftCategories[cs.category] = true
+			}
+			best = evaluateCategoricalSplit(data, column, leftCategories, bestMissingLeft, config)
+		}
+	} else {
+		directions := []bool{false}
+		if hasMissing {
+			directions = append(directions, true)
+		}
+		for trial:=0; trial<len(present); trial++ {
+			leftCategories := make([]bool, categoryCount)
+			leftCount := 0
+			for leftCount == 0 || leftCount == len(present) {
+				leftCount = 0
+				for _,cs := range present {
+					leftCategories[cs.category] = config.rng.Intn(2) == 0
+					if leftCategories[cs.category] {
+						leftCount += 1
+					}
+				}
+			}
+			for _,missingLeft := range directions {
+				if candidate := evaluateCategoricalSplit(data, column, leftCategories, missingLeft, config); candidate.compositeSplitMetric < best.compositeSplitMetric {
+					best = candidate
+				}
+			}
+		}
+	}
+	if !hasMissing {
+		best.missingLeft = heavierIsLeft(best.left, best.right)
+	}
+	return best
+}
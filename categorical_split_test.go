@@ -0,0 +1,125 @@
+package ML
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestCategoricalFeatureSplit (t *testing.T) {
+	// Categories 1 and 3 have a low output.  Categories 0 and 2
+	// have a high output.
+	outputs := []float64{5.0, 1.0, 5.0, 1.0}
+	data := make([]*Data, 0)
+	for i:=0; i<12; i++ {
+		c := i % 4
+		data = append(data, &Data{
+			categoricalFeatures: []int{0, c},
+			output: outputs[c],
+			outputCategories: 1,
+			weight: 1.0})
+	}
+
+	config := NewTree(StatAccumulatorFactory())
+	splitInfo := categoricalFeatureSplit(data, 1, config)
+	if splitInfo.featureType != CATEGORICAL {
+		t.Errorf ("expected a categorical split")
+	}
+	if splitInfo.compositeSplitMetric != 0.0 {
+		t.Errorf ("expected a perfect split; got metric %v", splitInfo.compositeSplitMetric)
+	}
+	for c,l := range splitInfo.leftCategories {
+		if l != (outputs[c] == 1.0) {
+			t.Errorf ("category %d with output %g sent left: %v", c, outputs[c], l)
+		}
+	}
+
+	// A column with a single category cannot be split.
+	splitInfo = categoricalFeatureSplit(data, 0, config)
+	if splitInfo.left.Count() != 0 {
+		t.Errorf ("expected no split on a constant column; got %v", splitInfo.leftCategories)
+	}
+}
+
+func TestCategoricalTree (t *testing.T) {
+	// Multiclass output determined by a pair of categorical columns.
+	data := make([]*Data, 0)
+	for i:=0; i<60; i++ {
+		a := i % 5
+		b := (i / 5) % 3
+		output := float64((a + b) % 3)
+		data = append(data, &Data{
+			categoricalFeatures: []int{a, b},
+			output: output,
+			outputCategories: 3,
+			weight: 1.0})
+	}
+
+	tree := NewTree(EntropyAccumulatorFactory(3))
+	tree.SetFeaturesToTry(2)
+	tree.SetRand(rand.New(rand.NewSource(3)))
+	tree.Train(data)
+
+	for _,d := range data {
+		if e := tree.Classify(d.FeatureSelector()).Estimate(); e != d.output {
+			t.Errorf ("%v classified as %g; expected %g", d.categoricalFeatures, e, d.output)
+		}
+	}
+
+	// Unseen categories follow the right branch.
+	unseen := &Data{categoricalFeatures: []int{7, 9}}
+	rightmost := tree.root
+	for rightmost.seed != -1 {
+		rightmost = rightmost.right
+	}
+	if c := tree.Classify(unseen.FeatureSelector()); c.Estimate() != rightmost.statistics.Estimate() || c.Count() != rightmost.statistics.Count() {
+		t.Errorf ("unseen categories classified as %g from %d records; expected the rightmost leaf's %g from %d", c.Estimate(), c.Count(), rightmost.statistics.Estimate(), rightmost.statistics.Count())
+	}
+
+	var b bytes.Buffer
+	if err := tree.Save(&b); err != nil {
+		t.Fatalf ("Save() failed: %v", err)
+	}
+	var loaded Tree
+	if err := loaded.Load(&b); err != nil {
+		t.Fatalf ("Load() failed: %v", err)
+	}
+	if dumpString(tree) != dumpString(&loaded) {
+		t.Errorf ("loaded tree differs:\n%s\nexpected:\n%s", dumpString(&loaded), dumpString(tree))
+	}
+}
+
+func TestCategoricalMissingValues (t *testing.T) {
+	// Records with a missing category (-1) have the output of
+	// category 2.
+	outputs := []float64{1.0, 1.0, 5.0}
+	data := make([]*Data, 0)
+	for i:=0; i<12; i++ {
+		c := i % 4 - 1
+		output := outputs[2]
+		if c >= 0 {
+			output = outputs[c]
+		}
+		data = append(data, &Data{
+			categoricalFeatures: []int{c},
+			output: output,
+			outputCategories: 1,
+			weight: 1.0})
+	}
+
+	config := NewTree(StatAccumulatorFactory())
+	splitInfo := categoricalFeatureSplit(data, 0, config)
+	if splitInfo.compositeSplitMetric != 0.0 {
+		t.Errorf ("expected a perfect split; got metric %v", splitInfo.compositeSplitMetric)
+	}
+	if splitInfo.missingLeft != splitInfo.leftCategories[2] {
+		t.Errorf ("missing values and category 2 split apart: %v, %v", splitInfo.missingLeft, splitInfo.leftCategories)
+	}
+
+	tree := NewTree(StatAccumulatorFactory())
+	tree.Train(data)
+	missing := &Data{categoricalFeatures: []int{-1}}
+	if e := tree.Classify(missing.FeatureSelector()).Estimate(); e != 5.0 {
+		t.Errorf ("missing category classified as %g; expected 5", e)
+	}
+}
@@ -2,8 +2,10 @@ package ML
 
 import (
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"strconv"
 )
@@ -17,106 +19,331 @@ func fieldTypeCount (legend string, c rune) (result int) {
 	return result
 }
 
-// CSVData opens "filename" and interprets the data according to the characters in "legend".
-// Each character represent a single field as follows:
-// i - ignored field
-// f - feature (continuous-valued or categorical)
-// k - key (string)
-// r - regression output
-// c - categorical output
+// BadRowPolicy selects what CSVReader.Read() does with a record that
+// cannot be decoded.
+type BadRowPolicy int
 
-func CSVData(legend string, filename string, outputCategories, skip int) []*Data {
-	result := make([]*Data,0)
+const (
+	// StopOnBadRow makes Read() return the first error.
+	StopOnBadRow BadRowPolicy = iota
+	// SkipBadRows makes Read() drop bad records silently.
+	SkipBadRows
+	// CollectBadRows makes Read() drop bad records and return all
+	// of their errors, along with the good records, in a
+	// BadRowsError.
+	CollectBadRows
+)
 
-	var file io.ReadCloser
-	var err error
+// CSVError describes a record of a CSV file that cannot be decoded.
+type CSVError struct {
+	Line int // Line number in the file, starting at 1
+	Column int // Field number in the record, starting at 1, or 0 if the whole record is bad
+	Value string // The offending field
+	Err error
+}
 
-	if file, err = os.Open(filename); err != nil {
-		panic (fmt.Sprintf ("Unable to open file \"%s\" for input", filename))
+func (e *CSVError) Error() string {
+	if e.Column == 0 {
+		return fmt.Sprintf("line %d: %v", e.Line, e.Err)
 	}
-	
-	var output float64
-	csvReader := csv.NewReader(file)
+	return fmt.Sprintf("line %d, column %d (\"%s\"): %v", e.Line, e.Column, e.Value, e.Err)
+}
+
+func (e *CSVError) Unwrap() error {
+	return e.Err
+}
 
-	var fields []string
-	var key string
+// BadRowsError is returned by CSVReader.Read() under the
+// CollectBadRows policy when any records were dropped.
+type BadRowsError []*CSVError
+
+func (e BadRowsError) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	return fmt.Sprintf("%d bad records; first: %v", len(e), e[0])
+}
+
+var (
+	ErrFieldCount = errors.New("wrong number of fields")
+	ErrNotNumeric = errors.New("numeric value expected")
+	ErrCategoryRange = errors.New("output category out of range")
+	ErrMissingOutput = errors.New("output value is missing")
+)
+
+// DefaultMissingValues are the field values that CSVReader treats as
+// missing unless its MissingValues are set.
+var DefaultMissingValues = []string{"", "NA", "?"}
+
+// CSVReader decodes CSV records according to the characters in
+// "Legend".  Each character represents a single field as follows,
+// and fields with any other character are ignored:
+// i - ignored field
+// f - continuous feature (a number)
+// n - categorical feature, whose string values are encoded with
+//     Dictionaries.Features
+// k - key (string); the default key is the record number
+// r - regression output (a number)
+// c - categorical output: a category number below OutputCategories,
+//     or a string label if StringLabels is true
+// Records select their "f" fields with column selectors (see
+// UseColumnSelectors()), so they can be used for training directly.
+// Missing feature values are read as NaN for "f" fields and as
+// category -1 for "n" fields.  Trees send them down the branch
+// chosen for missing values at each split.  Records with a missing
+// output are bad records.
+type CSVReader struct {
+	Legend string
+	// OutputCategories is 1 for a regression output and the number
+	// of categories for a categorical output.
+	OutputCategories int
+	// Skip is the number of records, such as headers, to skip at
+	// the beginning of the input.
+	Skip int
+	// If StringLabels is true, the "c" column holds string class
+	// labels, which are encoded with Dictionaries.Output.  The
+	// number of output categories is then the larger of
+	// OutputCategories and the number of distinct labels.
+	StringLabels bool
+	// Dictionaries encode the "n" columns and string class labels.
+	// Read() creates them if they are nil.  To encode a test file
+	// like its training file, reuse the training dictionaries.
+	Dictionaries *CSVDictionaries
+	BadRows BadRowPolicy
+	// MissingValues are the field values that denote a missing
+	// value.  DefaultMissingValues are used if it is nil.
+	MissingValues []string
+}
 
+func NewCSVReader(legend string, outputCategories int) *CSVReader {
+	return &CSVReader{
+		Legend: legend,
+		OutputCategories: outputCategories}
+}
+
+// Read() decodes all records from "r".  Records that cannot be
+// decoded are handled according to the reader's BadRows policy.
+func (cr *CSVReader) Read(r io.Reader) ([]*Data, error) {
+	if cr.Dictionaries == nil {
+		cr.Dictionaries = newCSVDictionaries(cr.Legend)
+	}
+	legend := []rune(cr.Legend)
+	result := make([]*Data,0)
+	var badRows BadRowsError
+
+	csvReader := csv.NewReader(r)
+	// Field counts are checked by decode() so that they are
+	// reported like other bad records.
+	csvReader.FieldsPerRecord = -1
+
+	skip := cr.Skip
 	recordCount := 0
-	for fields,err = csvReader.Read(); err==nil; fields,err = csvReader.Read() {
-		recordCount += 1
-		// Default key is the record number
-		// Any field may be used as the key by using the "k" indicator in legend.
-		key = strconv.FormatInt(int64(recordCount),10)
-		
-		if len(fields) != len(legend) {
-			panic (fmt.Sprintf("Wrong number of fields: got %d expected %d", len(fields), len(legend)))
+	for {
+		fields,err := csvReader.Read()
+		if err == io.EOF {
+			break
 		}
+		recordCount += 1
 		if (skip > 0) {
 			skip--
 			continue
 		}
-		
-
-		features := make([]float64,fieldTypeCount(legend, 'f'))
-		featureCount := 0
-		for i,c := range legend {
-			switch c {
-			case 'k':	// Key
-				key = fields[i]
-			case 'f':	// Feature
-				features[featureCount],err = strconv.ParseFloat(fields[i],64)
-				if err!=nil {
-					panic (fmt.Sprintf("Numeric value expected: %s", fields[i]))
-				}
-				featureCount += 1
-			case 'i':	// Ignored
-			case 'r':	// Regression output
-				output,err = strconv.ParseFloat(fields[i],64)
-				if err!=nil {
-					panic (fmt.Sprintf("Numeric value expected: %s", fields[i]))
-				}
-			case 'c':	// Categorical output
-				output,err = strconv.ParseFloat(fields[i],64)
-				if err!=nil {
-					panic (fmt.Sprintf("Numeric value expected: %s", fields[i]))
-				}
-				if output > float64(outputCategories) {
-					panic (fmt.Sprintf("Output value %g larger than output categories: %d", output, outputCategories))
-				}
+
+		var d *Data
+		if err != nil {
+			var parseError *csv.ParseError
+			if !errors.As(err, &parseError) {
+				return nil, err
 			}
-			
+			err = &CSVError{Line: parseError.Line, Err: parseError.Err}
+		} else {
+			d,err = cr.decode(csvReader, legend, fields, recordCount)
 		}
-		var errorAccumulator WeightedErrorAccumulator
-		if outputCategories == 1 {
-			errorAccumulator = &WeightedStatAccumulator{}
-		} else if outputCategories > 1 {
-			errorAccumulator = NewWeightedEntropyAccumulator(outputCategories)
+
+		if err != nil {
+			switch cr.BadRows {
+			case StopOnBadRow:
+				return nil, err
+			case CollectBadRows:
+				badRows = append(badRows, err.(*CSVError))
+			}
+			continue
 		}
-		
-		result = append(result, &Data {
-			key: key,
-			continuousFeatures: features,
-			categoricalFeatures: nil,
-			output: output,
-			outputCategories: outputCategories,
-			oobAccumulator: errorAccumulator})
-	}
-	
-	if err != io.EOF {
-		panic (err)
+		result = append(result, d)
 	}
-	
-	file.Close()
-	
-	return result
+
+	outputCategories := cr.OutputCategories
+	if cr.StringLabels && fieldTypeCount(cr.Legend, 'c') > 0 && cr.Dictionaries.Output.Len() > outputCategories {
+		outputCategories = cr.Dictionaries.Output.Len()
+	}
+	for _,d := range result {
+		d.outputCategories = outputCategories
+		d.oobAccumulator = newVoteAccumulator(outputCategories)
+	}
+
+	if len(badRows) > 0 {
+		return result, badRows
+	}
+	return result, nil
 }
 
+// ReadFile() opens "filename" and decodes it with Read().
+func (cr *CSVReader) ReadFile(filename string) ([]*Data, error) {
+	file,err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return cr.Read(file)
+}
 
+// isMissing() returns true if "field" denotes a missing value.
+func (cr *CSVReader) isMissing(field string) bool {
+	missingValues := cr.MissingValues
+	if missingValues == nil {
+		missingValues = DefaultMissingValues
+	}
+	for _,m := range missingValues {
+		if field == m {
+			return true
+		}
+	}
+	return false
+}
 
+// decode() converts the fields of one record into a Data record.
+// Any error is a *CSVError.
+func (cr *CSVReader) decode(csvReader *csv.Reader, legend []rune, fields []string, recordCount int) (*Data, error) {
+	if len(fields) != len(legend) {
+		line,_ := csvReader.FieldPos(0)
+		return nil, &CSVError{Line: line, Err: fmt.Errorf("%w: got %d expected %d", ErrFieldCount, len(fields), len(legend))}
+	}
 
+	fieldError := func(i int, err error) error {
+		line,_ := csvReader.FieldPos(i)
+		return &CSVError{Line: line, Column: i+1, Value: fields[i], Err: err}
+	}
 
+	// Default key is the record number
+	// Any field may be used as the key by using the "k" indicator in legend.
+	key := strconv.FormatInt(int64(recordCount),10)
 
+	var err error
+	var output float64
+	features := make([]float64,fieldTypeCount(cr.Legend, 'f'))
+	featureCount := 0
+	var categories []int
+	if n := fieldTypeCount(cr.Legend, 'n'); n > 0 {
+		categories = make([]int, n)
+	}
+	categoryCount := 0
+	// String fields are encoded only once the whole record has been
+	// validated, so a rejected record adds no levels to the
+	// dictionaries.  categoryFields[j] is the field of categorical
+	// feature j, or -1 if it is missing.
+	categoryFields := make([]int, len(categories))
+	labelField := -1
+	for i,c := range legend {
+		if (c == 'r' || c == 'c') && cr.isMissing(fields[i]) {
+			return nil, fieldError(i, ErrMissingOutput)
+		}
+		switch c {
+		case 'k':	// Key
+			key = fields[i]
+		case 'f':	// Feature
+			if cr.isMissing(fields[i]) {
+				features[featureCount] = math.NaN()
+				featureCount += 1
+				break
+			}
+			features[featureCount],err = strconv.ParseFloat(fields[i],64)
+			if err!=nil {
+				return nil, fieldError(i, ErrNotNumeric)
+			}
+			featureCount += 1
+		case 'n':	// Categorical feature
+			if cr.isMissing(fields[i]) {
+				categoryFields[categoryCount] = -1
+			} else {
+				categoryFields[categoryCount] = i
+			}
+			categoryCount += 1
+		case 'i':	// Ignored
+		case 'r':	// Regression output
+			output,err = strconv.ParseFloat(fields[i],64)
+			if err!=nil {
+				return nil, fieldError(i, ErrNotNumeric)
+			}
+			if math.IsNaN(output) {
+				return nil, fieldError(i, ErrMissingOutput)
+			}
+		case 'c':	// Categorical output
+			if cr.StringLabels {
+				labelField = i
+				break
+			}
+			output,err = strconv.ParseFloat(fields[i],64)
+			if err!=nil {
+				return nil, fieldError(i, ErrNotNumeric)
+			}
+			if math.IsNaN(output) {
+				return nil, fieldError(i, ErrMissingOutput)
+			}
+			if output < 0.0 || output >= float64(cr.OutputCategories) {
+				return nil, fieldError(i, fmt.Errorf("%w: %g not in [0,%d)", ErrCategoryRange, output, cr.OutputCategories))
+			}
+		}
+	}
+
+	for j,i := range categoryFields {
+		if i == -1 {
+			categories[j] = -1
+		} else {
+			categories[j] = cr.Dictionaries.Features[j].Index(fields[i])
+		}
+	}
+	if labelField != -1 {
+		output = float64(cr.Dictionaries.Output.Index(fields[labelField]))
+	}
 
+	d := &Data {
+		key: key,
+		continuousFeatures: features,
+		categoricalFeatures: categories,
+		output: output,
+		weight: 1.0}
+	UseColumnSelectors([]*Data{d})
+	return d, nil
+}
 
+// CSVData opens "filename" and interprets the data according to the
+// characters in "legend" (see CSVReader).  It panics if the file
+// cannot be read or decoded.
+func CSVData(legend string, filename string, outputCategories, skip int) []*Data {
+	reader := NewCSVReader(legend, outputCategories)
+	reader.Skip = skip
+	return mustRead(reader.ReadFile(filename))
+}
 
+// CSVDataWithDictionaries is like CSVData except that the "c" column
+// holds string class labels, which are encoded as categories along
+// with the "n" columns.  Encoding starts from "dictionaries", so a
+// test file can be encoded like the training file it accompanies.
+// If "dictionaries" is nil, new ones are created.  The dictionaries
+// are returned so that predicted categories can be mapped back to
+// labels.  The number of output categories is the larger of
+// "outputCategories" and the number of distinct labels.
+func CSVDataWithDictionaries(legend string, filename string, outputCategories, skip int, dictionaries *CSVDictionaries) ([]*Data, *CSVDictionaries) {
+	reader := NewCSVReader(legend, outputCategories)
+	reader.Skip = skip
+	reader.StringLabels = true
+	reader.Dictionaries = dictionaries
+	return mustRead(reader.ReadFile(filename)), reader.Dictionaries
+}
 
+func mustRead(data []*Data, err error) []*Data {
+	if err != nil {
+		panic (err)
+	}
+	return data
+}
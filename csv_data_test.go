@@ -0,0 +1,204 @@
+package ML
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, name, contents string) string {
+	filename := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filename, []byte(contents), 0644); err != nil {
+		t.Fatalf ("Unable to write \"%s\": %v", filename, err)
+	}
+	return filename
+}
+
+func TestCSVDataWithDictionaries (t *testing.T) {
+	train := writeTestFile(t, "train.csv",
+		"a,red,1.5,setosa\n" +
+		"b,green,2.5,versicolor\n" +
+		"c,red,3.5,virginica\n" +
+		"d,blue,4.5,setosa\n")
+	test := writeTestFile(t, "test.csv",
+		"e,blue,1.0,virginica\n" +
+		"f,purple,2.0,setosa\n")
+
+	data,dictionaries := CSVDataWithDictionaries("knfc", train, 0, 0, nil)
+	if len(data) != 4 {
+		t.Fatalf ("expected 4 records; got %d", len(data))
+	}
+	if dictionaries.Output.Len() != 3 || data[0].outputCategories != 3 {
+		t.Errorf ("expected 3 output categories; got %d", dictionaries.Output.Len())
+	}
+	expectedColors := []int{0, 1, 0, 2}
+	expectedOutputs := []float64{0, 1, 2, 0}
+	for i,d := range data {
+		if d.categoricalFeatures[0] != expectedColors[i] || d.output != expectedOutputs[i] {
+			t.Errorf ("record %s encoded as %v/%g; expected %d/%g", d.key, d.categoricalFeatures, d.output, expectedColors[i], expectedOutputs[i])
+		}
+	}
+	if dictionaries.Features[0].Level(2) != "blue" || dictionaries.Output.Level(1) != "versicolor" {
+		t.Errorf ("dictionaries do not map indices back to levels")
+	}
+
+	testData,dictionaries := CSVDataWithDictionaries("knfc", test, 0, 0, dictionaries)
+	if testData[0].categoricalFeatures[0] != 2 || testData[0].output != 2.0 {
+		t.Errorf ("test data not encoded with training dictionaries: %v/%g", testData[0].categoricalFeatures, testData[0].output)
+	}
+	if testData[1].categoricalFeatures[0] != 3 {
+		t.Errorf ("new level encoded as %d; expected 3", testData[1].categoricalFeatures[0])
+	}
+
+	// Numeric class labels still work with "n" columns in CSVData.
+	numeric := writeTestFile(t, "numeric.csv", "red,1\ngreen,0\n")
+	data = CSVData("nc", numeric, 2, 0)
+	if data[0].categoricalFeatures[0] != 0 || data[1].categoricalFeatures[0] != 1 || data[0].output != 1.0 {
+		t.Errorf ("CSVData decoded %v/%g and %v/%g", data[0].categoricalFeatures, data[0].output, data[1].categoricalFeatures, data[1].output)
+	}
+}
+
+func TestCSVReaderErrors (t *testing.T) {
+	input := "1.0,2.0,1\n" +
+		"1.0,oops,0\n" +
+		"1.0,2.0\n" +
+		"3.0,4.0,5\n" +
+		"5.0,6.0,0\n"
+
+	reader := NewCSVReader("ffc", 2)
+	data,err := reader.Read(strings.NewReader(input))
+	if data != nil {
+		t.Errorf ("expected no data on error; got %d records", len(data))
+	}
+	var csvError *CSVError
+	if !errors.As(err, &csvError) || csvError.Line != 2 || csvError.Column != 2 || !errors.Is(err, ErrNotNumeric) {
+		t.Errorf ("expected a non-numeric error at line 2, column 2; got %v", err)
+	}
+
+	reader.BadRows = SkipBadRows
+	data,err = reader.Read(strings.NewReader(input))
+	if err != nil || len(data) != 2 {
+		t.Errorf ("expected 2 records and no error when skipping; got %d and %v", len(data), err)
+	}
+
+	reader.BadRows = CollectBadRows
+	data,err = reader.Read(strings.NewReader(input))
+	var badRows BadRowsError
+	if !errors.As(err, &badRows) || len(data) != 2 {
+		t.Fatalf ("expected 2 records and a BadRowsError when collecting; got %d and %v", len(data), err)
+	}
+	expected := []struct{ line int; err error }{{2, ErrNotNumeric}, {3, ErrFieldCount}, {4, ErrCategoryRange}}
+	if len(badRows) != len(expected) {
+		t.Fatalf ("expected %d bad rows; got %v", len(expected), badRows)
+	}
+	for i,e := range expected {
+		if badRows[i].Line != e.line || !errors.Is(badRows[i], e.err) {
+			t.Errorf ("bad row %d: expected line %d, %v; got %v", i, e.line, e.err, badRows[i])
+		}
+	}
+
+	if _,err := reader.ReadFile(filepath.Join(t.TempDir(), "missing.csv")); err == nil {
+		t.Errorf ("expected an error for a missing file")
+	}
+}
+
+func TestCSVBadRowLevels (t *testing.T) {
+	// The bad field follows the string fields of its row.
+	input := "setosa,red,1.0\n" +
+		"virginica,green,oops\n" +
+		"setosa,blue,2.0\n"
+
+	for _,badRows := range []BadRowPolicy{SkipBadRows, CollectBadRows} {
+		reader := NewCSVReader("cnf", 0)
+		reader.StringLabels = true
+		reader.BadRows = badRows
+		data,_ := reader.Read(strings.NewReader(input))
+		if len(data) != 2 {
+			t.Fatalf ("expected 2 records; got %d", len(data))
+		}
+		if n := reader.Dictionaries.Features[0].Len(); n != 2 {
+			t.Errorf ("expected 2 feature levels after a rejected row; got %d", n)
+		}
+		if n := reader.Dictionaries.Output.Len(); n != 1 {
+			t.Errorf ("expected 1 output level after a rejected row; got %d", n)
+		}
+		if data[1].categoricalFeatures[0] != 1 || data[1].output != 0.0 {
+			t.Errorf ("record after a rejected row encoded as %v/%g", data[1].categoricalFeatures, data[1].output)
+		}
+	}
+}
+
+func TestCSVMissingValues (t *testing.T) {
+	input := "1.5,red,1\n" +
+		",NA,2\n" +
+		"?,blue,3\n" +
+		"2.5,red,NA\n"
+
+	reader := NewCSVReader("fnr", 1)
+	reader.BadRows = CollectBadRows
+	data,err := reader.Read(strings.NewReader(input))
+	var badRows BadRowsError
+	if !errors.As(err, &badRows) || len(badRows) != 1 || !errors.Is(badRows[0], ErrMissingOutput) {
+		t.Errorf ("expected one missing output error; got %v", err)
+	}
+	if len(data) != 3 {
+		t.Fatalf ("expected 3 records; got %d", len(data))
+	}
+	if !math.IsNaN(data[1].continuousFeatures[0]) || !math.IsNaN(data[2].continuousFeatures[0]) || data[0].continuousFeatures[0] != 1.5 {
+		t.Errorf ("expected missing features to be NaN; got %v, %v, %v", data[0].continuousFeatures, data[1].continuousFeatures, data[2].continuousFeatures)
+	}
+	if data[1].categoricalFeatures[0] != -1 || data[2].categoricalFeatures[0] != 1 {
+		t.Errorf ("expected categories -1 and 1; got %v and %v", data[1].categoricalFeatures, data[2].categoricalFeatures)
+	}
+	if !math.IsNaN(data[1].feature(categoricalSeed(0))) {
+		t.Errorf ("expected a missing category to select NaN")
+	}
+
+	// Only the configured values are missing.
+	reader = NewCSVReader("fr", 1)
+	reader.MissingValues = []string{"-"}
+	if _,err := reader.Read(strings.NewReader("NA,1\n")); !errors.Is(err, ErrNotNumeric) {
+		t.Errorf ("expected NA to be an error; got %v", err)
+	}
+	if data,err := reader.Read(strings.NewReader("-,1\n")); err != nil || !math.IsNaN(data[0].continuousFeatures[0]) {
+		t.Errorf ("expected \"-\" to be missing; got %v", err)
+	}
+}
+
+func TestCSVColumnSelectors (t *testing.T) {
+	// The output is determined by the third column.
+	input := ""
+	for i:=0; i<30; i++ {
+		output := 0
+		if i % 6 >= 3 {
+			output = 1
+		}
+		input += fmt.Sprintf("%d,%d,%d,%s,%d\n", i % 4, i % 5, i % 6, []string{"a", "b"}[i % 2], output)
+	}
+	data,err := NewCSVReader("fffnc", 2).Read(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf ("Read() failed: %v", err)
+	}
+	if v := data[7].FeatureSelector()(2); v != 1.0 {
+		t.Errorf ("column 2 of record 7 selected as %g; expected 1", v)
+	}
+
+	tree := NewTree(EntropyAccumulatorFactory(2))
+	tree.SetFeaturesToTry(4)
+	tree.Train(data)
+	if tree.root.seed != 2 || tree.Size() != 3 {
+		t.Errorf ("expected a single split on column 2; got:\n%s", dumpString(tree))
+	}
+	for seed,_ := range tree.Importance() {
+		if seed != 2 {
+			t.Errorf ("unexpected importance for seed %d", seed)
+		}
+	}
+	if e := tree.Classify(ColumnSelector([]float64{0, 0, 4})).Estimate(); e != 1.0 {
+		t.Errorf ("new record classified as %g; expected 1", e)
+	}
+}
@@ -1,5 +1,11 @@
 package ML
 
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
 type Feature interface {
 	// compareTo() is only required to work for features of the same type
 	// e.g., continuous or categorical.
@@ -15,7 +21,19 @@ type Data struct {
 	output float64
 	outputCategories int // 0 means no output, 1 means continuous
 
+	// weight is the sample weight of the record, used by trees
+	// built with NewWeightedTree() and by the boosting models.  The
+	// CSV readers set it to 1; otherwise it is 0 until set with
+	// SetWeight(), and trainers that use weights reject records
+	// whose total weight is not positive (see
+	// requirePositiveWeight()).
+	weight float64
+
 	featureSelector func (int32) float64
+	// selectsColumns is true if featureSelector selects a column of
+	// continuousFeatures (see UseColumnSelectors()), in which case
+	// trees try distinct columns at each node.
+	selectsColumns bool
 	oobAccumulator WeightedErrorAccumulator
 }
 
@@ -27,9 +45,123 @@ func (d *Data) Features() []float64 {
 	return d.continuousFeatures
 }
 
+// feature() returns the value of the feature selected by "seed".
+// Seeds less than -1 select categorical features (see
+// categoricalSeed()); a missing category is returned as NaN.  Other
+// seeds are passed to the record's feature selector.
+func (d *Data) feature(seed int32) float64 {
+	if seed < -1 {
+		category := d.categoricalFeatures[categoricalColumn(seed)]
+		if category < 0 {
+			return math.NaN()
+		}
+		return float64(category)
+	}
+	return d.featureSelector(seed)
+}
+
+// FeatureSelector() returns a feature selector for the record that
+// selects both its categorical and its continuous features.  Use it
+// to classify records that have categorical features.
+func (d *Data) FeatureSelector() func(int32) float64 {
+	return d.feature
+}
+
+// SetFeatureSelector() sets the function that returns the record's
+// feature for a seed.  The same seed must always select the same
+// feature of every record.
+func (d *Data) SetFeatureSelector(selector func(int32) float64) {
+	d.featureSelector = selector
+	d.selectsColumns = false
+}
+
+// ColumnSelector() returns a feature selector for which seed s
+// selects features[s % len(features)].
+func ColumnSelector(features []float64) func(int32) float64 {
+	return func(seed int32) float64 {
+		return features[int(seed) % len(features)]
+	}
+}
+
+// column() is the feature selector set by UseColumnSelectors().  It
+// reads continuousFeatures on every call, so it sees features added
+// by AppendFeatures().
+func (d *Data) column(seed int32) float64 {
+	return d.continuousFeatures[int(seed) % len(d.continuousFeatures)]
+}
+
+// UseColumnSelectors() makes the feature selector of each record in
+// "data" select the columns of its continuous features like
+// ColumnSelector().  Trees then try SetFeaturesToTry() distinct
+// columns, continuous or categorical, at each node.  Records read
+// by CSVReader already use column selectors.
+func UseColumnSelectors(data []*Data) {
+	for _,d := range data {
+		if len(d.continuousFeatures) > 0 {
+			d.featureSelector = d.column
+			d.selectsColumns = true
+		}
+	}
+}
+
+// Weight() returns the sample weight of the record.
+func (d *Data) Weight() float64 {
+	return d.weight
+}
+
+// SetWeight() sets the sample weight of the record.  Records read by
+// the CSV readers have weight 1; others have weight 0 until it is
+// set, and must be given weights before training a weighted tree
+// (see NewWeightedTree()), GradientBoosting or AdaBoost, which panic
+// on a training set of zero total weight.
+func (d *Data) SetWeight(weight float64) {
+	d.weight = weight
+}
+
+// requirePositiveWeight() panics unless "data" is empty or the total
+// weight of its records is positive.  "caller" names the trainer in
+// the message.
+func requirePositiveWeight(data []*Data, caller string) {
+	total := 0.0
+	for _,d := range data {
+		total += d.weight
+	}
+	if len(data) > 0 && !(total > 0.0) {
+		panic(errors.New(fmt.Sprintf("%s called with %d records of total weight %g; see Data.SetWeight()", caller, len(data), total)))
+	}
+}
+
+// sortableData sorts records by the value of the feature selected
+// by "seed".  Feature values are computed once, when the
+// sortableData is created, rather than on every comparison.
 type sortableData struct {
 	data []*Data
-	seed int32
+	values []float64
+}
+
+func newSortableData(data []*Data, seed int32) sortableData {
+	values := make([]float64, len(data))
+	for i,d := range data {
+		values[i] = d.featureSelector(seed)
+	}
+	return sortableData{data, values}
+}
+
+// partitionMissing() moves the records whose feature value is
+// missing (NaN) to the end and returns the number of other records.
+func (s sortableData) partitionMissing() int {
+	present := 0
+	for i,v := range s.values {
+		if !math.IsNaN(v) {
+			s.Swap(i, present)
+			present += 1
+		}
+	}
+	return present
+}
+
+func (s sortableData) slice(from, to int) sortableData {
+	return sortableData{s.data[from:to], s.values[from:to]}
 }
 
 func (s sortableData) Len() int {
@@ -37,11 +169,12 @@ func (s sortableData) Len() int {
 }
 
 func (s sortableData) Less(i, j int) bool {
-	return s.data[i].featureSelector(s.seed) < s.data[j].featureSelector(s.seed)
+	return s.values[i] < s.values[j]
 }
 
 func (s sortableData) Swap(i, j int) {
 	s.data[i],s.data[j] = s.data[j],s.data[i]
+	s.values[i],s.values[j] = s.values[j],s.values[i]
 }
 
 
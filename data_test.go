@@ -3,6 +3,7 @@ package ML
 import (
 	"fmt"
 	"image"
+	"runtime"
 	"testing"
 	"time"
 	"os"
@@ -47,10 +48,10 @@ func TestDigitData (t *testing.T) {
 		for i,f := range digitData[i].continuousFeatures {
 			pix[i] = uint8(f)
 		}
-		grayImage := image.Gray{pix,28,image.Rect(0,0,28,28)}
-//		gwf := GrayWithFeatures{&grayImage,0,0.0,nil}
+		grayImage := image.Gray{Pix: pix, Stride: 28, Rect: image.Rect(0,0,28,28)}
+//		gwf := GrayWithFeatures{Gray: &grayImage}
 		hf := NewHierarchicalFeatures(&grayImage)
-		digitData[i].featureSelector = func (s int32) float64 { return hf.RandomFeature(s) }
+		digitData[i].SetFeatureSelector(func (s int32) float64 { return hf.RandomFeature(s) })
 	}
 
 //	start = time.Now()
@@ -65,32 +66,20 @@ func TestDigitData (t *testing.T) {
 
 //	f := continuousFeatureEntropySplitter (digitData[0].outputCategories)
 	ensemble := NewEnsemble()
-
-	for i:=0; i<10000; i++ {
+	ensemble.SetClassifierFactory(func () Classifier {
 		newTree := NewTree(EntropyAccumulatorFactory(digitData[0].outputCategories))
 
 		// Tunable parameters
 		newTree.SetFeaturesToTry(80)
 		newTree.SetMinLeafSize(1)
 		newTree.SetMaxDepth(30)
+		return newTree
+	})
 
-		TrainBag(digitData, newTree)
-		ensemble.AddClassifier(newTree)
-		fmt.Printf ("Tree %d stats - size: %d  depth: %d leaves %d performance: %g\n",
-			i, newTree.Size(), newTree.Depth(), newTree.Leaves(), newTree.Estimate())
+	const treesPerRound = 100
+	for i:=0; i<100; i++ {
+		ensemble.Train(digitData, treesPerRound, runtime.NumCPU(), int64(i))
 		mserror := ensemble.Error(digitData)
-		if i % 1 == 0 {
-			fmt.Printf ("Trees: %d: ensemble error=%g\n", i, mserror)
-		}
+		fmt.Printf ("Trees: %d: ensemble error=%g\n", (i+1)*treesPerRound, mserror)
 	}		
 }
-
-
-
-
-
-
-
-
-
-
@@ -0,0 +1,52 @@
+package ML
+
+import (
+	"fmt"
+)
+
+// A FeatureDescriber decodes the seed of a feature into a
+// human-readable description of the feature it selects.
+type FeatureDescriber interface {
+	DescribeFeature(s int32) string
+}
+
+// FeatureDescriberFunc adapts an ordinary function to the
+// FeatureDescriber interface.
+type FeatureDescriberFunc func(s int32) string
+
+func (f FeatureDescriberFunc) DescribeFeature(s int32) string {
+	return f(s)
+}
+
+// describeFeature() describes the feature selected by "seed" with
+// "describer", or by its seed or categorical column if "describer"
+// is nil.
+func describeFeature(describer FeatureDescriber, seed int32) string {
+	if describer != nil {
+		return describer.DescribeFeature(seed)
+	}
+	if seed < -1 {
+		return fmt.Sprintf("categorical feature %d", categoricalColumn(seed))
+	}
+	return fmt.Sprintf("feature %d", seed)
+}
+
+// ColumnDescriber() returns a FeatureDescriber for tabular records
+// that names continuous feature s%len(continuous) after
+// "continuous" and categorical columns after "categorical".  Either
+// may be nil, in which case features are described by number.
+func ColumnDescriber(continuous, categorical []string) FeatureDescriber {
+	return FeatureDescriberFunc(func(s int32) string {
+		if s < -1 {
+			column := categoricalColumn(s)
+			if column < len(categorical) {
+				return categorical[column]
+			}
+			return describeFeature(nil, s)
+		}
+		if len(continuous) > 0 && s >= 0 {
+			return continuous[int(s) % len(continuous)]
+		}
+		return describeFeature(nil, s)
+	})
+}
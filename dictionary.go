@@ -0,0 +1,61 @@
+package ML
+
+// A Dictionary encodes the string values of a categorical column as
+// category indices.  Indices are assigned in order of first
+// appearance, starting at zero.
+type Dictionary struct {
+	levels []string
+	indices map[string]int
+}
+
+func NewDictionary() *Dictionary {
+	return &Dictionary{
+		levels: make([]string, 0),
+		indices: make(map[string]int)}
+}
+
+// Index() returns the index of "level", adding it to the dictionary
+// if it has not been seen before.
+func (d *Dictionary) Index(level string) int {
+	if i,ok := d.indices[level]; ok {
+		return i
+	}
+	d.indices[level] = len(d.levels)
+	d.levels = append(d.levels, level)
+	return len(d.levels) - 1
+}
+
+// Lookup() returns the index of "level" without adding it.
+func (d *Dictionary) Lookup(level string) (index int, ok bool) {
+	index,ok = d.indices[level]
+	return index, ok
+}
+
+// Level() returns the string value encoded as "index".
+func (d *Dictionary) Level(index int) string {
+	return d.levels[index]
+}
+
+func (d *Dictionary) Len() int {
+	return len(d.levels)
+}
+
+// CSVDictionaries holds the dictionaries used to encode the string
+// valued columns of a CSV file.
+type CSVDictionaries struct {
+	// Features has one dictionary for each "n" column of the
+	// legend.  Features[i] encodes categorical feature i.
+	Features []*Dictionary
+	// Output encodes the "c" column when class labels are strings.
+	Output *Dictionary
+}
+
+func newCSVDictionaries(legend string) *CSVDictionaries {
+	dictionaries := &CSVDictionaries{
+		Features: make([]*Dictionary, fieldTypeCount(legend, 'n')),
+		Output: NewDictionary()}
+	for i,_ := range dictionaries.Features {
+		dictionaries.Features[i] = NewDictionary()
+	}
+	return dictionaries
+}
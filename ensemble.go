@@ -3,44 +3,197 @@ package ML
 import (
 //	"fmt"
 //	"os"
+	"math/rand"
+	"sync"
 )
 
 type Ensemble struct {
 	errorAccumulator ErrorAccumulator
 	classifiers []Classifier
+
+	// classifierFactory creates the classifiers trained by Train().
+	classifierFactory func() Classifier
+
+	// sampler draws the bag for each classifier trained by Train().
+	sampler Sampler
+
+	// oob[i] holds the indices of the out-of-bag records of
+	// classifiers[i] if it was trained by Train() and is nil
+	// otherwise.
+	oob [][]int
+}
+
+// randomizedClassifier is implemented by classifiers, such as *Tree,
+// whose training draws from a random source that can be set.
+type randomizedClassifier interface {
+	SetRand(rng *rand.Rand)
+}
+
+// baggedClassifier holds a classifier trained by Train() along with
+// its predictions for its out-of-bag records.
+type baggedClassifier struct {
+	index int
+	classifier Classifier
+	oob []int
+	predictions []float64
 }
 
 func NewEnsemble() *Ensemble {
 	return &Ensemble{
 		errorAccumulator: &errorAccumulator{},
-		classifiers: make([]Classifier,0,1000)}
+		classifiers: make([]Classifier,0,1000),
+		sampler: SubsampleSampler(2.0/3.0)}
 }
 
+// TrainBag() trains "classifier" on a random two thirds of "data",
+// drawn without replacement.
 func TrainBag (data[]*Data, classifier Classifier) {
-	trainSize := 2*len(data)/3
-
-	// Shuffle data and take first "trainSize" samples as the bag or training set.
-	ShuffleData(data)
-	trainSet := data[0:trainSize]
-	classifier.Train (trainSet)
-	
-	// Use remaining samples as the "out-of-bag" test set.  Each
-	// classifier gets its own out-of-bag test set.  All of the
+	TrainBagWithSampler(data, classifier, SubsampleSampler(2.0/3.0), rand.New(rand.NewSource(rand.Int63())))
+}
+
+// TrainBagWithSampler() trains "classifier" on the bag drawn from
+// "data" by "sampler".
+func TrainBagWithSampler (data []*Data, classifier Classifier, sampler Sampler, rng *rand.Rand) {
+	bc := trainBagged(data, classifier, sampler, rng)
+
+	// The out-of-bag records are the test set.  Each classifier
+	// gets its own out-of-bag test set.  All of the
 	// classifications for all classifiers are accumulated within
 	// the test record's oobAccumulator.  The ensemble
 	// classification (over all classifiers used to classify the
 	// record, which is not all classifiers) may be retrieved by
 	// oobAccumulator.Estimate().
-	testSet := data[trainSize:]
-	for _,d := range testSet {
-		prediction := classifier.Classify(d.featureSelector).Estimate()
-		d.oobAccumulator.Add (prediction, 1.0)
-		classifier.Add (d.output - prediction)
+	bc.addVotes(data)
+}
+
+// newVoteAccumulator() returns an accumulator for combining the
+// predictions of several classifiers.  Categorical predictions are
+// counted as votes.  Continuous predictions are averaged.
+func newVoteAccumulator(outputCategories int) WeightedCVAccumulator {
+	if outputCategories == 1 {
+		return &WeightedStatAccumulator{}
+	} else if outputCategories > 1 {
+		return NewWeightedEntropyAccumulator(outputCategories)
+	}
+	return nil
+}
+
+// SetClassifierFactory() sets the function used by Train() to create
+// new classifiers.
+func (te *Ensemble) SetClassifierFactory (factory func() Classifier) {
+	te.classifierFactory = factory
+}
+
+// SetSampler() sets the Sampler used by Train() to draw the bag for
+// each classifier.  The default draws two thirds of the data without
+// replacement.
+func (te *Ensemble) SetSampler (sampler Sampler) {
+	te.sampler = sampler
+}
+
+// Train() trains "n" new classifiers on bags drawn from "data" by the
+// ensemble's Sampler and adds them to the ensemble.  Classifiers are trained concurrently on
+// "workers" goroutines, but each one has its own bag and its own
+// random source derived from "seed", and out-of-bag votes are added
+// in the order the classifiers were created.  The result for a given
+// seed is therefore the same for any number of workers.  "data" is
+// not reordered.  Feature selectors must be safe for concurrent use.
+func (te *Ensemble) Train (data []*Data, n, workers int, seed int64) {
+	if te.classifierFactory == nil {
+		panic ("Train() called without a classifier factory")
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	source := rand.New(rand.NewSource(seed))
+	seeds := make([]int64, n)
+	for i,_ := range seeds {
+		seeds[i] = source.Int63()
+	}
+
+	jobs := make(chan int)
+	results := make(chan *baggedClassifier)
+
+	var wg sync.WaitGroup
+	for w:=0; w<workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				classifier := te.classifierFactory()
+				bc := trainBagged(data, classifier, te.sampler, rand.New(rand.NewSource(seeds[i])))
+				bc.index = i
+				results <- bc
+			}
+		}()
+	}
+
+	go func() {
+		for i:=0; i<n; i++ {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	// Only this goroutine modifies the ensemble and the out-of-bag
+	// accumulators.  Results that arrive early wait in "pending"
+	// until all of their predecessors have been added.
+	pending := make(map[int]*baggedClassifier)
+	next := 0
+	for r := range results {
+		pending[r.index] = r
+		for pending[next] != nil {
+			te.AddClassifier(pending[next].classifier)
+			te.oob[len(te.oob)-1] = pending[next].oob
+			pending[next].addVotes(data)
+			delete(pending, next)
+			next += 1
+		}
+	}
+}
+
+// trainBagged() trains "classifier" on the bag drawn by "sampler"
+// and classifies the out-of-bag records.
+func trainBagged (data []*Data, classifier Classifier, sampler Sampler, rng *rand.Rand) *baggedClassifier {
+	if rc,ok := classifier.(randomizedClassifier); ok {
+		rc.SetRand(rng)
+	}
+
+	bag,oob := sampler(data, rng)
+	trainSet := make([]*Data, len(bag))
+	for i,j := range bag {
+		trainSet[i] = data[j]
+	}
+	classifier.Train(trainSet)
+
+	predictions := make([]float64, len(oob))
+	for i,j := range oob {
+		d := data[j]
+		predictions[i] = classifier.Classify(d.FeatureSelector()).Estimate()
+		classifier.Add(d.output - predictions[i])
+	}
+	return &baggedClassifier{
+		classifier: classifier,
+		oob: oob,
+		predictions: predictions}
+}
+
+// addVotes() adds the out-of-bag predictions of the classifier to
+// the oobAccumulator of each out-of-bag record.
+func (bc *baggedClassifier) addVotes (data []*Data) {
+	for i,j := range bc.oob {
+		if data[j].oobAccumulator != nil {
+			data[j].oobAccumulator.Add(bc.predictions[i], 1.0)
+		}
 	}
 }
 
 func (te *Ensemble) AddClassifier (newClassifier Classifier) {
 	te.classifiers = append(te.classifiers, newClassifier)
+	te.oob = append(te.oob, nil)
 }
 
 func (te *Ensemble) Error (data[]*Data) float64 {
@@ -58,3 +211,25 @@ func (te *Ensemble) Error (data[]*Data) float64 {
 	}
 	return te.errorAccumulator.Estimate()
 }
+
+// Predict() classifies a new record with every classifier in the
+// ensemble.  It returns the ensemble estimate and the accumulated
+// votes.  For categorical outputs the votes are a
+// *WeightedEntropyAccumulator holding the number of classifiers
+// voting for each category.  For continuous outputs they are a
+// *WeightedStatAccumulator holding the mean and variance of the
+// individual predictions.
+func (te *Ensemble) Predict (featureSelector func(int32) float64) (float64, WeightedCVAccumulator) {
+	var votes WeightedCVAccumulator
+	for _,c := range te.classifiers {
+		statistics := c.Classify(featureSelector)
+		if votes == nil {
+			votes = newVoteAccumulator(outputCategories(statistics))
+		}
+		votes.Add(statistics.Estimate(), 1.0)
+	}
+	if votes == nil {
+		panic ("Predict() called on an ensemble with no classifiers")
+	}
+	return votes.Estimate(), votes
+}
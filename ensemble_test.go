@@ -0,0 +1,91 @@
+package ML
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEnsemblePredict (t *testing.T) {
+	// Every seed selects the third feature, whose values are
+	// distinct, so each tree fits the data whatever its random seeds.
+	data := persistenceTestData()
+	for _,d := range data {
+		features := d.continuousFeatures
+		d.featureSelector = func (s int32) float64 { return features[2] }
+	}
+
+	classification := NewEnsemble()
+	regression := NewEnsemble()
+	for i:=0; i<7; i++ {
+		tree := NewTree(EntropyAccumulatorFactory(3))
+		tree.SetFeaturesToTry(3)
+		tree.Train(data)
+		classification.AddClassifier(tree)
+
+		weightedTree := NewWeightedTree(WeightedStatAccumulatorFactory())
+		weightedTree.SetFeaturesToTry(3)
+		weightedTree.Train(data)
+		regression.AddClassifier(weightedTree)
+	}
+
+	for _,d := range data {
+		estimate,votes := classification.Predict(d.featureSelector)
+		if estimate != d.output {
+			t.Errorf ("%v classified as %g; expected %g", d.continuousFeatures, estimate, d.output)
+		}
+		ea,ok := votes.(*WeightedEntropyAccumulator)
+		if !ok {
+			t.Fatalf ("classification votes have type %T", votes)
+		}
+		if ea.Count() != 7 || ea.Probability(d.output) != 1.0 {
+			t.Errorf ("expected 7 unanimous votes for %g; got %v", d.output, ea)
+		}
+
+		estimate,votes = regression.Predict(d.featureSelector)
+		if estimate != d.output || votes.Metric() != 0.0 {
+			t.Errorf ("%v predicted as %g (variance %g); expected %g", d.continuousFeatures, estimate, votes.Metric(), d.output)
+		}
+		if _,ok := votes.(*WeightedStatAccumulator); !ok {
+			t.Errorf ("regression votes have type %T", votes)
+		}
+	}
+}
+
+func TestEnsembleTrain (t *testing.T) {
+	train := func (workers int) ([]*Data, *Ensemble) {
+		data := persistenceTestData()
+		for _,d := range data {
+			d.oobAccumulator = newVoteAccumulator(3)
+		}
+		ensemble := NewEnsemble()
+		ensemble.SetClassifierFactory(func () Classifier {
+			tree := NewTree(EntropyAccumulatorFactory(3))
+			tree.SetFeaturesToTry(2)
+			return tree
+		})
+		ensemble.Train(data, 20, workers, 12345)
+		return data, ensemble
+	}
+
+	serialData,serial := train(1)
+	parallelData,parallel := train(4)
+
+	if len(serial.classifiers) != 20 || len(parallel.classifiers) != 20 {
+		t.Fatalf ("expected 20 classifiers; got %d and %d", len(serial.classifiers), len(parallel.classifiers))
+	}
+	for i,c := range serial.classifiers {
+		checkSameTree(t, "parallel", c.(*Tree), parallel.classifiers[i].(*Tree), serialData)
+	}
+
+	var serialVotes, parallelVotes bytes.Buffer
+	for i,d := range serialData {
+		d.oobAccumulator.Dump(&serialVotes, 0)
+		parallelData[i].oobAccumulator.Dump(&parallelVotes, 0)
+	}
+	if serialVotes.String() != parallelVotes.String() {
+		t.Errorf ("out-of-bag votes depend on the number of workers")
+	}
+	if serial.Error(serialData) != parallel.Error(parallelData) {
+		t.Errorf ("out-of-bag error depends on the number of workers")
+	}
+}
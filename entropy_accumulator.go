@@ -56,6 +56,27 @@ func (ea *EntropyAccumulator) Remove(category float64) {
 	ea.counts[int(category)] -= 1
 }
 
+// Merge() adds the category counts of "other", which must be an
+// *EntropyAccumulator with the same number of categories.
+func (ea *EntropyAccumulator) Merge(other ErrorAccumulator) {
+	o,ok := other.(*EntropyAccumulator)
+	if !ok {
+		panic(errors.New(fmt.Sprintf("Cannot merge %T into an EntropyAccumulator", other)))
+	}
+	ea.mergeCounts(o)
+}
+
+// mergeCounts() adds the category counts of "o" to those of "ea".
+func (ea *EntropyAccumulator) mergeCounts(o *EntropyAccumulator) {
+	if len(o.counts) != len(ea.counts) {
+		panic(errors.New(fmt.Sprintf("Cannot merge %d categories into %d", len(o.counts), len(ea.counts))))
+	}
+	for i,c := range o.counts {
+		ea.counts[i] += c
+	}
+	ea.totalCount += o.totalCount
+}
+
 func (ea *EntropyAccumulator) Count() int {
 	return ea.totalCount
 }
@@ -1,6 +1,7 @@
 package ML
 
 import (
+	"errors"
 	"fmt"
 	"io"
 )
@@ -23,6 +24,17 @@ func (ea *errorAccumulator) Clone() ErrorAccumulator {
 		errorCount: ea.errorCount}
 }
 
+// Merge() adds the records counted by "other", which must be an
+// *errorAccumulator.
+func (ea *errorAccumulator) Merge(other ErrorAccumulator) {
+	o,ok := other.(*errorAccumulator)
+	if !ok {
+		panic(errors.New(fmt.Sprintf("Cannot merge %T into an errorAccumulator", other)))
+	}
+	ea.totalCount += o.totalCount
+	ea.errorCount += o.errorCount
+}
+
 func (ea *errorAccumulator) Count() int {
 	return ea.totalCount
 }
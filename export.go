@@ -0,0 +1,162 @@
+package ML
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ExportedNode is the structure of a tree node as exported by
+// Tree.Export().  Unlike the records written by Tree.SaveJSON(), it
+// is meant to be read by people and by other programs, not loaded
+// back.
+type ExportedNode struct {
+	// ID numbers the nodes in depth-first order from 0 at the root.
+	ID int `json:"id"`
+	// Count and WeightedCount are the number and weight of the
+	// training records that reached the node.
+	Count int `json:"count"`
+	WeightedCount float64 `json:"weightedCount"`
+	Estimate float64 `json:"estimate"`
+	Metric float64 `json:"metric"`
+	// Distribution is the (weighted) count of each output category
+	// for categorical outputs.  Mean and Variance are set for
+	// continuous outputs accumulated by a StatAccumulator or a
+	// QuantileAccumulator.
+	Distribution []float64 `json:"distribution,omitempty"`
+	Mean *float64 `json:"mean,omitempty"`
+	Variance *float64 `json:"variance,omitempty"`
+
+	// The remaining fields are set only for splits.  Feature is the
+	// description of the split feature (see FeatureDescriber) and
+	// Seed selects it.  Records go left if the feature is less than
+	// Threshold, for continuous features, or in LeftCategories, for
+	// categorical ones.
+	Feature string `json:"feature,omitempty"`
+	Seed int32 `json:"seed"`
+	Threshold *float64 `json:"threshold,omitempty"`
+	LeftCategories []int `json:"leftCategories,omitempty"`
+	MissingLeft bool `json:"missingLeft,omitempty"`
+	Left *ExportedNode `json:"left,omitempty"`
+	Right *ExportedNode `json:"right,omitempty"`
+}
+
+// Export() returns the structure of the tree, describing split
+// features with the tree's FeatureDescriber, or nil if the tree has
+// not been trained.
+func (tree *Tree) Export() *ExportedNode {
+	if tree.root == nil {
+		return nil
+	}
+	id := 0
+	return tree.root.export(tree.featureDescriber, &id)
+}
+
+// ExportJSON() writes the structure returned by Export() to "w" as
+// JSON.
+func (tree *Tree) ExportJSON(w io.Writer) error {
+	if tree.root == nil {
+		return errUntrained
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(tree.Export())
+}
+
+func (tree *treeNode) export(describer FeatureDescriber, id *int) *ExportedNode {
+	e := &ExportedNode{
+		ID: *id,
+		Count: tree.statistics.Count(),
+		WeightedCount: tree.statistics.WeightedCount(),
+		Estimate: tree.statistics.Estimate(),
+		Metric: tree.statistics.Metric(),
+		Seed: tree.seed}
+	*id += 1
+
+	switch inner := innerAccumulator(tree.statistics).(type) {
+	case categoricalAccumulator:
+		e.Distribution = inner.distribution()
+	case *StatAccumulator, *WeightedStatAccumulator, *QuantileAccumulator:
+		mean := tree.statistics.Estimate()
+		variance := tree.statistics.Metric()
+		e.Mean = &mean
+		e.Variance = &variance
+	}
+
+	if tree.seed == -1 {
+		return e
+	}
+	e.Feature = describeFeature(describer, tree.seed)
+	if tree.featureType == CATEGORICAL {
+		e.LeftCategories,_ = tree.categorySets()
+	} else {
+		threshold := tree.splitValue
+		e.Threshold = &threshold
+	}
+	e.MissingLeft = tree.missingLeft
+	e.Left = tree.left.export(describer, id)
+	e.Right = tree.right.export(describer, id)
+	return e
+}
+
+// WriteDOT() writes the tree to "w" as a Graphviz DOT graph.  Each
+// node shows its split, the number of training records that reached
+// it, and its class distribution or mean and variance.
+func (tree *Tree) WriteDOT(w io.Writer) error {
+	if tree.root == nil {
+		return errUntrained
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "digraph Tree {\n")
+	fmt.Fprintf(bw, "\tnode [shape=box, fontname=\"helvetica\"];\n")
+	writeDOTNode(bw, tree.Export())
+	fmt.Fprintf(bw, "}\n")
+	return bw.Flush()
+}
+
+func writeDOTNode(w io.Writer, e *ExportedNode) {
+	lines := make([]string, 0, 4)
+	if e.Left != nil {
+		if e.Threshold != nil {
+			lines = append(lines, fmt.Sprintf("%s < %g", e.Feature, *e.Threshold))
+		} else {
+			lines = append(lines, fmt.Sprintf("%s in %v", e.Feature, e.LeftCategories))
+		}
+	}
+	lines = append(lines, fmt.Sprintf("samples = %d", e.Count))
+	if e.Distribution != nil {
+		lines = append(lines, fmt.Sprintf("value = %v", e.Distribution))
+	} else if e.Mean != nil {
+		lines = append(lines, fmt.Sprintf("mean = %g; variance = %g", *e.Mean, *e.Variance))
+	}
+	lines = append(lines, fmt.Sprintf("estimate = %g", e.Estimate))
+
+	shape := ""
+	if e.Left == nil {
+		shape = ", style=rounded"
+	}
+	fmt.Fprintf(w, "\t%d [label=\"%s\"%s];\n", e.ID, dotEscape(strings.Join(lines, "\n")), shape)
+
+	if e.Left != nil {
+		leftLabel, rightLabel := "yes", "no"
+		if e.MissingLeft {
+			leftLabel = "yes, missing"
+		} else {
+			rightLabel = "no, missing"
+		}
+		fmt.Fprintf(w, "\t%d -> %d [label=\"%s\"];\n", e.ID, e.Left.ID, leftLabel)
+		fmt.Fprintf(w, "\t%d -> %d [label=\"%s\"];\n", e.ID, e.Right.ID, rightLabel)
+		writeDOTNode(w, e.Left)
+		writeDOTNode(w, e.Right)
+	}
+}
+
+// dotEscape() escapes "s" for use in a quoted DOT string.  Newlines
+// become line breaks.
+func dotEscape(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	s = strings.ReplaceAll(s, "\"", "\\\"")
+	return strings.ReplaceAll(s, "\n", "\\n")
+}
@@ -0,0 +1,92 @@
+package ML
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// checkExportedCounts() checks that the children of each split in
+// "e" partition its records.
+func checkExportedCounts(t *testing.T, e *ExportedNode) {
+	if e.Left == nil {
+		return
+	}
+	if e.Left.Count + e.Right.Count != e.Count {
+		t.Errorf ("node %d: children have %d and %d records; expected %d in all", e.ID, e.Left.Count, e.Right.Count, e.Count)
+	}
+	checkExportedCounts(t, e.Left)
+	checkExportedCounts(t, e.Right)
+}
+
+func TestExport (t *testing.T) {
+	data := persistenceTestData()
+
+	classification := NewTree(EntropyAccumulatorFactory(3))
+	classification.SetFeaturesToTry(3)
+	classification.SetRand(rand.New(rand.NewSource(2)))
+	classification.SetFeatureDescriber(ColumnDescriber([]string{"a", "b", "c"}, nil))
+	classification.Train(data)
+
+	root := classification.Export()
+	if root.Count != len(data) || root.Feature == "" || root.Threshold == nil {
+		t.Errorf ("unexpected root %+v", root)
+	}
+	total := 0.0
+	for _,c := range root.Distribution {
+		total += c
+	}
+	if int(total) != len(data) || len(root.Distribution) != 3 {
+		t.Errorf ("root distribution %v does not cover %d records", root.Distribution, len(data))
+	}
+	checkExportedCounts(t, root)
+
+	var b bytes.Buffer
+	if err := classification.ExportJSON(&b); err != nil {
+		t.Fatalf ("ExportJSON() failed: %v", err)
+	}
+	var decoded ExportedNode
+	if err := json.Unmarshal(b.Bytes(), &decoded); err != nil {
+		t.Fatalf ("exported JSON does not decode: %v", err)
+	}
+	checkExportedCounts(t, &decoded)
+
+	b.Reset()
+	if err := classification.WriteDOT(&b); err != nil {
+		t.Fatalf ("WriteDOT() failed: %v", err)
+	}
+	dot := b.String()
+	if !strings.HasPrefix(dot, "digraph Tree {") || strings.Count(dot, "->") != classification.Size() - 1 {
+		t.Errorf ("unexpected DOT output:\n%s", dot)
+	}
+
+	regression := NewWeightedTree(WeightedStatAccumulatorFactory())
+	regression.SetMaxDepth(1)
+	regression.Train(data)
+	root = regression.Export()
+	if root.Mean == nil || root.Variance == nil || *root.Mean != root.Estimate || root.Distribution != nil {
+		t.Errorf ("expected a mean and variance at the root; got %+v", root)
+	}
+	if root.Left == nil || root.Left.Left != nil {
+		t.Errorf ("expected a single split")
+	}
+}
+
+func TestExportUntrained (t *testing.T) {
+	tree := NewTree(EntropyAccumulatorFactory(3))
+	if e := tree.Export(); e != nil {
+		t.Errorf ("untrained tree exported as %+v", e)
+	}
+	var b bytes.Buffer
+	if err := tree.ExportJSON(&b); err == nil {
+		t.Errorf ("ExportJSON() accepted an untrained tree")
+	}
+	if err := tree.WriteDOT(&b); err == nil {
+		t.Errorf ("WriteDOT() accepted an untrained tree")
+	}
+	if b.Len() != 0 {
+		t.Errorf ("untrained tree wrote %q", b.String())
+	}
+}
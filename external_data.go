@@ -1,17 +1,63 @@
 package ML
 
+import (
+	"io"
+	"os"
+)
+
+// ReadGlassData() reads the UCI glass identification data from "r".
+// Bad records are handled according to "badRows".
+func ReadGlassData(r io.Reader, badRows BadRowPolicy) ([]*Data, error) {
+	reader := NewCSVReader("ifffffffffc", 8)
+	reader.BadRows = badRows
+	return reader.Read(r)
+}
+
+// GlassData() reads the glass identification data from "filename".
+// It panics on any error.
 func GlassData(filename string) []*Data {
-	return CSVData("ifffffffffc", filename, 8, 0)
+	file,err := os.Open(filename)
+	if err != nil {
+		panic (err)
+	}
+	defer file.Close()
+	return mustRead(ReadGlassData(file, StopOnBadRow))
 }
 
-func DigitData(filename string) []*Data {
+// ReadDigitData() reads the Kaggle digit recognizer data (a header,
+// then a label and 784 pixel values per record) from "r" and adds
+// features derived from the pixels.  Bad records are handled
+// according to "badRows".
+func ReadDigitData(r io.Reader, badRows BadRowPolicy) ([]*Data, error) {
 	legend := "c"
 	for i:=0; i<784; i++ {
 		legend = legend + "f"
 	}
+	reader := NewCSVReader(legend, 10)
+	reader.Skip = 1
+	reader.BadRows = badRows
+
+	data,err := reader.Read(r)
+	if data != nil {
+		addDigitFeatures(data)
+	}
+	return data, err
+}
 
-	data := CSVData(legend, filename, 10, 1)
+// DigitData() reads the digit data from "filename".  It panics on
+// any error.
+func DigitData(filename string) []*Data {
+	file,err := os.Open(filename)
+	if err != nil {
+		panic (err)
+	}
+	defer file.Close()
+	return mustRead(ReadDigitData(file, StopOnBadRow))
+}
 
+// addDigitFeatures() appends features derived from the pixels of
+// each digit image.
+func addDigitFeatures(data []*Data) {
 	for _,d := range data {
 		f := d.Features()
 		
@@ -51,7 +97,6 @@ func DigitData(filename string) []*Data {
 		d.AppendFeatures([]float64{topBottom})
 		d.AppendFeatures([]float64{leftRight})
 	}
-	return data
 }
 
 
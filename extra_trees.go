@@ -0,0 +1,95 @@
+package ML
+
+import (
+	"math"
+)
+
+// SetExtraTrees() makes the tree an extremely randomized tree: each
+// candidate continuous feature is split at a single cut point drawn
+// uniformly between its smallest and largest value at the node,
+// rather than at the best of all cut points.  Training is much
+// faster, as no sorting is needed, and ensembles of such trees trade
+// some bias for lower variance.  Categorical features and the choice
+// among the SetFeaturesToTry() candidates are unaffected.  Random
+// splits take precedence over binned splits (see SetMaxBins()).
+func (tree *Tree) SetExtraTrees(extraTrees bool) {
+	tree.extraTrees = extraTrees
+}
+
+// randomCutSplit() splits "data" on the feature selected by "seed"
+// at a cut point drawn from config.rng between the smallest and
+// largest values of the feature.  Missing values are sent to the
+// side that gives the lower metric.  If the feature has fewer than
+// two distinct values, every record is put on the right, so the
+// split is never chosen.
+func randomCutSplit(data []*Data, seed int32, config *Tree) SplitInfo {
+	values := make([]float64, len(data))
+	low, high := math.Inf(1), math.Inf(-1)
+	for i,d := range data {
+		values[i] = d.featureSelector(seed)
+		if !math.IsNaN(values[i]) {
+			low = math.Min(low, values[i])
+			high = math.Max(high, values[i])
+		}
+	}
+
+	newAccumulator := func() WeightedCVAccumulator {
+		a := config.accumulatorFactory()
+		a.Clear()
+		return a
+	}
+	left, right, missing := newAccumulator(), newAccumulator(), newAccumulator()
+	if !(low < high) {
+		for _,row := range data {
+			right.Add(row.output, row.weight)
+		}
+		return SplitInfo{
+			compositeSplitMetric: right.Metric(),
+			featureType: CONTINUOUS,
+			left: left,
+			right: right}
+	}
+
+	// The smallest value must go left and the largest right.
+	cut := low + config.rng.Float64()*(high - low)
+	if cut <= low {
+		cut = math.Nextafter(low, math.Inf(1))
+	}
+	for i,row := range data {
+		switch {
+		case math.IsNaN(values[i]):
+			missing.Add(row.output, row.weight)
+		case values[i] < cut:
+			left.Add(row.output, row.weight)
+		default:
+			right.Add(row.output, row.weight)
+		}
+	}
+
+	splitInfo := SplitInfo{
+		featureType: CONTINUOUS,
+		splitValue: cut,
+		left: left,
+		right: right}
+	if missing.Count() == 0 {
+		splitInfo.compositeSplitMetric = compositeMetric(left, right)
+		splitInfo.missingLeft = heavierIsLeft(left, right)
+		return splitInfo
+	}
+
+	leftWithMissing := left.Clone().(WeightedCVAccumulator)
+	leftWithMissing.Merge(missing)
+	rightWithMissing := right.Clone().(WeightedCVAccumulator)
+	rightWithMissing.Merge(missing)
+	missingLeftMetric := compositeMetric(leftWithMissing, right)
+	missingRightMetric := compositeMetric(left, rightWithMissing)
+	if missingLeftMetric < missingRightMetric {
+		splitInfo.compositeSplitMetric = missingLeftMetric
+		splitInfo.missingLeft = true
+		splitInfo.left = leftWithMissing
+	} else {
+		splitInfo.compositeSplitMetric = missingRightMetric
+		splitInfo.right = rightWithMissing
+	}
+	return splitInfo
+}
@@ -0,0 +1,76 @@
+package ML
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestRandomCutSplit (t *testing.T) {
+	tree := NewTree(StatAccumulatorFactory())
+	tree.SetRand(rand.New(rand.NewSource(1)))
+	data := make([]*Data, 0)
+	for _,v := range []float64{3, 7, 5, 4, math.NaN()} {
+		data = append(data, &Data{continuousFeatures: []float64{v}, output: v, outputCategories: 1, weight: 1.0})
+	}
+	// Missing values resemble the large ones.
+	data[4].output = 7.0
+	UseColumnSelectors(data)
+
+	for i:=0; i<100; i++ {
+		split := randomCutSplit(data, 0, tree)
+		if split.splitValue <= 3 || split.splitValue > 7 {
+			t.Fatalf ("cut at %g is outside (3,7]", split.splitValue)
+		}
+		if split.left.Count() == 0 || split.right.Count() == 0 || split.left.Count() + split.right.Count() != 5 {
+			t.Fatalf ("bad partition %v", &split)
+		}
+		if split.splitValue <= 5 && split.missingLeft {
+			t.Errorf ("missing value sent left of the cut at %g", split.splitValue)
+		}
+	}
+
+	// A constant feature cannot be split.
+	for _,d := range data {
+		d.continuousFeatures[0] = 2.0
+	}
+	if split := randomCutSplit(data, 0, tree); split.left.Count() != 0 {
+		t.Errorf ("constant feature split: %v", &split)
+	}
+}
+
+func TestExtraTrees (t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	train := adaBoostTestData(300, rng, 3)
+	test := adaBoostTestData(300, rng, 3)
+
+	tree := NewTree(EntropyAccumulatorFactory(3))
+	tree.SetExtraTrees(true)
+	tree.SetFeaturesToTry(2)
+	tree.SetRand(rand.New(rand.NewSource(3)))
+	tree.Train(train)
+	for _,d := range train {
+		if tree.Classify(d.featureSelector).Estimate() != d.output {
+			t.Fatalf ("fully grown extra tree misclassifies training record %v", d.continuousFeatures)
+		}
+	}
+
+	ensemble := NewEnsemble()
+	ensemble.SetClassifierFactory(func() Classifier {
+		tree := NewTree(EntropyAccumulatorFactory(3))
+		tree.SetExtraTrees(true)
+		tree.SetFeaturesToTry(2)
+		return tree
+	})
+	ensemble.SetSampler(BootstrapSampler())
+	ensemble.Train(train, 50, 4, 4)
+	errors := 0
+	for _,d := range test {
+		if e,_ := ensemble.Predict(d.FeatureSelector()); e != d.output {
+			errors += 1
+		}
+	}
+	if errors > len(test)/10 {
+		t.Errorf ("extra trees misclassify %d of %d test records", errors, len(test))
+	}
+}
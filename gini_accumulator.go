@@ -0,0 +1,74 @@
+package ML
+
+import (
+	"errors"
+	"fmt"
+)
+
+// GiniAccumulator accumulates categorical outputs like
+// EntropyAccumulator, but its Metric() is the Gini impurity,
+// 1 - sum(p_i^2).  The sum of the squared category counts is kept up
+// to date so that Metric() takes constant time.
+type GiniAccumulator struct {
+	EntropyAccumulator
+	sumOfSquaredCounts int
+}
+
+func NewGiniAccumulator(categoryValueCount int) *GiniAccumulator {
+	return &GiniAccumulator{EntropyAccumulator: *NewEntropyAccumulator(categoryValueCount)}
+}
+
+func GiniAccumulatorFactory (categoryValueCount int) func() CVAccumulator {
+	return func() CVAccumulator {
+		return NewGiniAccumulator(categoryValueCount)
+	}
+}
+
+func (ga *GiniAccumulator) Clone() ErrorAccumulator {
+	return &GiniAccumulator{
+		EntropyAccumulator: *ga.EntropyAccumulator.Clone().(*EntropyAccumulator),
+		sumOfSquaredCounts: ga.sumOfSquaredCounts}
+}
+
+func (ga *GiniAccumulator) Add(category float64) {
+	ga.EntropyAccumulator.Add(category)
+	// (c+1)^2 - c^2 = 2c + 1, where c+1 is the new count
+	ga.sumOfSquaredCounts += 2*ga.counts[int(category)] - 1
+}
+
+func (ga *GiniAccumulator) Remove(category float64) {
+	ga.EntropyAccumulator.Remove(category)
+	// c^2 - (c-1)^2 = 2c - 1, where c-1 is the new count
+	ga.sumOfSquaredCounts -= 2*ga.counts[int(category)] + 1
+}
+
+// Merge() adds the category counts of "other", which must be a
+// *GiniAccumulator with the same number of categories.
+func (ga *GiniAccumulator) Merge(other ErrorAccumulator) {
+	o,ok := other.(*GiniAccumulator)
+	if !ok {
+		panic(errors.New(fmt.Sprintf("Cannot merge %T into a GiniAccumulator", other)))
+	}
+	ga.mergeCounts(&o.EntropyAccumulator)
+	ga.sumOfSquaredCounts = 0
+	for _,c := range ga.counts {
+		ga.sumOfSquaredCounts += c*c
+	}
+}
+
+func (ga *GiniAccumulator) Metric() float64 {
+	if ga.totalCount == 0 {
+		return 0.0
+	}
+	n := float64(ga.totalCount)
+	return 1.0 - float64(ga.sumOfSquaredCounts)/(n*n)
+}
+
+func (ga *GiniAccumulator) Clear() {
+	ga.EntropyAccumulator.Clear()
+	ga.sumOfSquaredCounts = 0
+}
+
+func (ga GiniAccumulator) String() string {
+	return fmt.Sprintf("gini%v", ga.EntropyAccumulator)
+}
@@ -8,12 +8,11 @@ import (
 	"io/ioutil"
 //	"os"
 	"math"
+	"strings"
 )
 
 type HierarchicalFeatures struct {
 	*image.Gray
-	memoSeed int32
-	memoValue float64
 	randomFeatureSelector RandomFeatureSelector
 	cumMass, cumXMass, cumYMass []int32
 	cumX2Mass, cumY2Mass, cumXYMass []int64
@@ -27,8 +26,6 @@ func NewHierarchicalFeatures(gs *image.Gray) *HierarchicalFeatures {
 	l := len(gs.Pix)
 	hf := HierarchicalFeatures{
 		Gray: gs,
-		memoSeed: -1,
-		memoValue: 0.0,
 		randomFeatureSelector: nil,
 		cumMass: make([]int32,l),
 		cumXMass: make([]int32,l),
@@ -208,24 +205,14 @@ func (hf *HierarchicalFeatures) Edges(r image.Rectangle) (horizEdges, vertEdges
 	return float64(horizSum)/float64(r.Dy()), float64(vertSum)/float64(r.Dx())
 }
 
+// RandomFeature() does not modify hf, so it may be called
+// concurrently by trees that are trained in parallel.
 func (hf *HierarchicalFeatures) RandomFeature(s int32) float64 {
 //	fmt.Fprintf (hierarchicalDebug, "RandomFeature(s=%d)\n", s)
-	// Use memoized lookups in case same "s" is used repeatedly
-	// (e.g., in sorts).
-//	fmt.Fprintf (hierarchicalDebug, "%*smemoSeed=%d, memoValue=%g\n", 3, "", hf.memoSeed, hf.memoValue)
-	if s == hf.memoSeed {
-		return hf.memoValue
-	}
-	
-	hf.memoSeed = s
-
 	depth := int(s % 5)
 	s = s / 5
 
-	hf.memoValue = hf.randomFeatureHelper(0, depth, s, image.Rect(0, 0, hf.Gray.Rect.Dx(), hf.Gray.Rect.Dy()), 0.0, 0.0)
-//	fmt.Fprintf (hierarchicalDebug, "\n")
-
-	return hf.memoValue
+	return hf.randomFeatureHelper(0, depth, s, image.Rect(0, 0, hf.Gray.Rect.Dx(), hf.Gray.Rect.Dy()), 0.0, 0.0)
 }
 
 func dbg (depth int, s string) {
@@ -363,3 +350,49 @@ func (hf *HierarchicalFeatures) randomFeatureHelper(depth int, remainingDepth in
 
 
 
+
+// hierarchicalPartitionNames and hierarchicalFeatureNames name the
+// partitions and attributes selected by randomFeatureHelper().
+var hierarchicalPartitionNames = []string{
+	"upper",
+	"lower",
+	"left",
+	"right",
+	"upper minus lower",
+	"left minus right"}
+
+var hierarchicalFeatureNames = []string{
+	"mass",
+	"x-bar displacement",
+	"y-bar displacement",
+	"sigma xx",
+	"sigma yy",
+	"sigma xy",
+	"moment of inertia",
+	"inertia determinant",
+	"vertical edges",
+	"horizontal edges"}
+
+// DescribeFeature() describes the feature selected by
+// RandomFeature(s) as its depth, the partitions chosen at each level
+// and the attribute of the final partition.  Partitions are split at
+// the centroid of their parent, so the description does not depend
+// on the image.
+func (hf *HierarchicalFeatures) DescribeFeature(s int32) string {
+	if s < 0 {
+		return describeFeature(nil, s)
+	}
+	depth := int(s % 5)
+	s = s / 5
+
+	region := "whole image"
+	if depth > 0 {
+		partitions := make([]string, depth)
+		for i,_ := range partitions {
+			partitions[i] = hierarchicalPartitionNames[s % 6]
+			s /= 6
+		}
+		region = strings.Join(partitions, " > ")
+	}
+	return fmt.Sprintf("depth-%d %s %s", depth, region, hierarchicalFeatureNames[s % 10])
+}
@@ -0,0 +1,232 @@
+package ML
+
+import (
+	"math"
+	"sort"
+)
+
+// binSampleFactor bounds the number of values from which the bin
+// edges of a feature are computed during growth to binSampleFactor
+// times the number of bins.
+const binSampleFactor = 32
+
+// quantileEdges() returns the edges of at most "maxBins" bins that
+// hold about the same number of the values in "values".  The edges
+// are distinct values from "values" in increasing order; bin k holds
+// the values from edges[k-1] up to but excluding edges[k].  If there
+// are no more distinct values than bins, each has its own bin.
+// Missing (NaN) values are ignored.
+func quantileEdges(values []float64, maxBins int) []float64 {
+	sorted := make([]float64, 0, len(values))
+	for _,v := range values {
+		if !math.IsNaN(v) {
+			sorted = append(sorted, v)
+		}
+	}
+	if len(sorted) == 0 || maxBins < 2 {
+		return []float64{}
+	}
+	sort.Float64s(sorted)
+
+	edges := make([]float64, 0, maxBins-1)
+	for i:=1; i<len(sorted) && len(edges) < maxBins; i++ {
+		if sorted[i] != sorted[i-1] {
+			edges = append(edges, sorted[i])
+		}
+	}
+	if len(edges) < maxBins {
+		return edges
+	}
+
+	edges = edges[0:0]
+	for i:=1; i<maxBins; i++ {
+		edge := sorted[i*len(sorted)/maxBins]
+		// An edge at the smallest value would leave the first bin
+		// empty.
+		if edge > sorted[0] && (len(edges) == 0 || edge > edges[len(edges)-1]) {
+			edges = append(edges, edge)
+		}
+	}
+	return edges
+}
+
+// binOf() returns the bin of "value" among the bins with "edges".
+func binOf(edges []float64, value float64) int {
+	return sort.Search(len(edges), func(i int) bool { return edges[i] > value })
+}
+
+// SetMaxBins() makes the tree find continuous splits by binning
+// feature values into at most "n" bins of about equal size and
+// splitting only at the bin edges, which is faster than
+// sorting the records at every node of a large training set, at
+// some cost in accuracy.  For records that select columns (see
+// UseColumnSelectors()), the bin edges of each column are the
+// quantiles of the whole training set, computed once by Train().
+// For other features they are computed at each node from a sample
+// of its records.  n == 0, the default, finds exact splits.
+func (tree *Tree) SetMaxBins(n int) {
+	tree.maxBins = n
+}
+
+// computeBinEdges() precomputes the bin edges of each column of
+// "trainingSet" if it is grown with binned splits.
+func (tree *Tree) computeBinEdges(trainingSet []*Data) {
+	tree.binEdges = nil
+	if tree.maxBins <= 0 || tree.extraTrees || len(trainingSet) == 0 || !trainingSet[0].selectsColumns {
+		return
+	}
+	tree.binEdges = make(map[int32][]float64)
+	values := make([]float64, len(trainingSet))
+	for c:=0; c<len(trainingSet[0].continuousFeatures); c++ {
+		seed := int32(c)
+		for i,d := range trainingSet {
+			values[i] = d.featureSelector(seed)
+		}
+		tree.binEdges[seed] = quantileEdges(values, tree.maxBins)
+	}
+}
+
+// continuousSplit() returns the best split of "data" on the
+// continuous feature selected by "seed", which is exact unless the
+// tree uses random or binned splits.
+func (tree *Tree) continuousSplit(data []*Data, seed int32) SplitInfo {
+	if tree.extraTrees {
+		return randomCutSplit(data, seed, tree)
+	}
+	if tree.maxBins > 0 {
+		return binnedFeatureSplit(data, seed, tree)
+	}
+	return continuousFeatureSplit(data, seed, tree.accumulatorFactory)
+}
+
+// binnedFeatureSplit() is like continuousFeatureSplit() except that
+// the outputs are accumulated in a histogram of the feature's bins
+// (see SetMaxBins()) and splits are only considered at bin edges.
+// The partitions of each candidate are merged from the histogram
+// rather than updated record by record.  Missing values are handled
+// the same way.
+func binnedFeatureSplit(data []*Data, seed int32, config *Tree) (splitInfo SplitInfo) {
+	newAccumulator := func() WeightedCVAccumulator {
+		a := config.accumulatorFactory()
+		a.Clear()
+		return a
+	}
+
+	values := make([]float64, len(data))
+	for i,d := range data {
+		values[i] = d.featureSelector(seed)
+	}
+
+	edges,ok := config.binEdges[seed]
+	if !ok {
+		sample := values
+		if limit := binSampleFactor*config.maxBins; len(values) > limit {
+			sample = make([]float64, limit)
+			for i,_ := range sample {
+				sample[i] = values[config.rng.Intn(len(values))]
+			}
+		}
+		edges = quantileEdges(sample, config.maxBins)
+	}
+
+	// The histogram has an accumulator for each bin that holds any
+	// records, in bin order, so small nodes stay cheap however many
+	// bins there are.  occupied[k] is the bin of histogram[k].
+	bins := make([]int, len(data))
+	slots := make([]int, len(edges) + 1)
+	maxValue := math.Inf(-1)
+	for i,v := range values {
+		if !math.IsNaN(v) {
+			bins[i] = binOf(edges, v)
+			slots[bins[i]] = 1
+			maxValue = math.Max(maxValue, v)
+		}
+	}
+	occupied := make([]int, 0)
+	for b,used := range slots {
+		if used != 0 {
+			slots[b] = len(occupied)
+			occupied = append(occupied, b)
+		}
+	}
+	histogram := make([]WeightedCVAccumulator, len(occupied))
+	for k,_ := range histogram {
+		histogram[k] = newAccumulator()
+	}
+	missing := newAccumulator()
+	for i,row := range data {
+		if math.IsNaN(values[i]) {
+			missing.Add(row.output, row.weight)
+		} else {
+			histogram[slots[bins[i]]].Add(row.output, row.weight)
+		}
+	}
+	hasMissing := missing.Count() > 0
+
+	// upper[k] accumulates histogram[k] and above.
+	upper := make([]WeightedCVAccumulator, len(histogram) + 1)
+	upper[len(histogram)] = newAccumulator()
+	for k:=len(histogram)-1; k>=0; k-- {
+		upper[k] = upper[k+1].Clone().(WeightedCVAccumulator)
+		upper[k].Merge(histogram[k])
+	}
+	withMissing := func(a WeightedCVAccumulator) WeightedCVAccumulator {
+		if !hasMissing {
+			return a
+		}
+		result := a.Clone().(WeightedCVAccumulator)
+		result.Merge(missing)
+		return result
+	}
+
+	splitInfo = SplitInfo {
+		featureType: CONTINUOUS,
+		splitValue: 0.0,
+		compositeSplitMetric: withMissing(upper[0]).Metric()}
+
+	// histogram[0:best] is the best left partition found so far.
+	best := 0
+	consider := func(splitValue float64, k int, l, r WeightedCVAccumulator, missingLeft bool) {
+		error := compositeMetric(l, r)
+		if error < splitInfo.compositeSplitMetric {
+			splitInfo.splitValue = splitValue
+			splitInfo.compositeSplitMetric = error
+			splitInfo.missingLeft = missingLeft
+			best = k
+		}
+	}
+
+	lower := newAccumulator()
+	for k:=1; k<len(histogram); k++ {
+		lower.Merge(histogram[k-1])
+		splitValue := edges[occupied[k]-1]
+		consider(splitValue, k, lower, withMissing(upper[k]), false)
+		if hasMissing {
+			consider(splitValue, k, withMissing(lower), upper[k], true)
+		}
+	}
+
+	// Split the records with a value from the missing ones.
+	if hasMissing && upper[0].Count() > 0 {
+		if above := math.Nextafter(maxValue, math.Inf(1)); !math.IsInf(above, 1) {
+			consider(above, len(histogram), upper[0], missing, false)
+		}
+	}
+
+	left := newAccumulator()
+	for _,h := range histogram[0:best] {
+		left.Merge(h)
+	}
+	right := upper[best]
+	if splitInfo.missingLeft {
+		left.Merge(missing)
+	} else {
+		right.Merge(missing)
+	}
+	if !hasMissing {
+		splitInfo.missingLeft = heavierIsLeft(left, right)
+	}
+	splitInfo.left = left
+	splitInfo.right = right
+	return
+}
@@ -0,0 +1,137 @@
+package ML
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestQuantileEdges (t *testing.T) {
+	values := make([]float64, 0)
+	for i:=100; i>0; i-- {
+		values = append(values, float64(i))
+	}
+	values = append(values, math.NaN())
+	if edges := quantileEdges(values, 4); !reflect.DeepEqual(edges, []float64{26, 51, 76}) {
+		t.Errorf ("expected edges [26 51 76]; got %v", edges)
+	}
+	if edges := quantileEdges([]float64{3, 3, 3, 3, 7}, 4); !reflect.DeepEqual(edges, []float64{7}) {
+		t.Errorf ("expected the repeated value to form one bin; got edges %v", edges)
+	}
+	for _,v := range []float64{0, 25.5, 26, 100} {
+		b := binOf([]float64{26, 51, 76}, v)
+		if (b == 0) != (v < 26) || (v == 100 && b != 3) {
+			t.Errorf ("%g is in bin %d", v, b)
+		}
+	}
+}
+
+func binnedTestData(threshold float64) []*Data {
+	data := make([]*Data, 0)
+	for i:=20; i>0; i-- {
+		output := 0.0
+		if float64(i) >= threshold {
+			output = 1.0
+		}
+		data = append(data, &Data{
+			continuousFeatures: []float64{float64(i)},
+			output: output,
+			outputCategories: 2,
+			weight: 1.0})
+	}
+	UseColumnSelectors(data)
+	return data
+}
+
+func TestBinnedSplit (t *testing.T) {
+	// With a bin per value, binned splits are exact.
+	tree := NewTree(EntropyAccumulatorFactory(2))
+	tree.SetMaxBins(32)
+	data := binnedTestData(9)
+	tree.computeBinEdges(data)
+	exact := continuousFeatureSplit(binnedTestData(9), 0, tree.accumulatorFactory)
+	binned := binnedFeatureSplit(data, 0, tree)
+	if binned.splitValue != exact.splitValue || binned.compositeSplitMetric != exact.compositeSplitMetric {
+		t.Errorf ("expected the exact split %v; got %v", &exact, &binned)
+	}
+
+	// Otherwise splits are at the bin edges.
+	tree.SetMaxBins(4)
+	for _,threshold := range []float64{11, 9} {
+		data := binnedTestData(threshold)
+		tree.computeBinEdges(data)
+		split := binnedFeatureSplit(data, 0, tree)
+		if split.splitValue != 11 && split.splitValue != 6 {
+			t.Errorf ("threshold %g: split at %g, which is not an edge of %v", threshold, split.splitValue, tree.binEdges[0])
+		}
+		if (split.compositeSplitMetric == 0.0) != (threshold == 11) {
+			t.Errorf ("threshold %g: unexpected split metric %g", threshold, split.compositeSplitMetric)
+		}
+		if split.left.Count() + split.right.Count() != 20 {
+			t.Errorf ("threshold %g: split lost records: %v", threshold, &split)
+		}
+	}
+
+	// Missing values go with the records they resemble, and
+	// features without precomputed edges are binned from the node.
+	data = binnedTestData(11)
+	for _,d := range data[0:3] {
+		d.continuousFeatures[0] = math.NaN()
+	}
+	tree.binEdges = nil
+	tree.SetMaxBins(32)
+	tree.SetRand(rand.New(rand.NewSource(1)))
+	split := binnedFeatureSplit(data, 0, tree)
+	if split.missingLeft || split.compositeSplitMetric != 0.0 {
+		t.Errorf ("expected missing values on the right of a perfect split; got %v", &split)
+	}
+}
+
+func TestBinnedTree (t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	data := make([]*Data, 0)
+	for i:=0; i<2000; i++ {
+		features := []float64{rng.Float64(), rng.Float64(), rng.NormFloat64()}
+		output := 0.0
+		if features[0] + features[1] > 1.0 {
+			output = 1.0
+		}
+		data = append(data, &Data{continuousFeatures: features, output: output, outputCategories: 2, weight: 1.0})
+	}
+	UseColumnSelectors(data)
+
+	tree := NewTree(EntropyAccumulatorFactory(2))
+	tree.SetFeaturesToTry(3)
+	tree.SetMaxBins(16)
+	tree.SetRand(rand.New(rand.NewSource(4)))
+	tree.Train(data)
+
+	errors := 0
+	for _,d := range data {
+		if tree.Classify(d.featureSelector).Estimate() != d.output {
+			errors += 1
+		}
+	}
+	if errors > len(data)/100 {
+		t.Errorf ("binned tree misclassifies %d of %d training records", errors, len(data))
+	}
+
+	// Every split is at a precomputed edge.
+	var check func(node *treeNode)
+	check = func(node *treeNode) {
+		if node.seed == -1 {
+			return
+		}
+		found := false
+		for _,e := range tree.binEdges[node.seed] {
+			found = found || e == node.splitValue
+		}
+		if !found {
+			t.Errorf ("split on %d at %g is not at a bin edge", node.seed, node.splitValue)
+		}
+		check(node.left)
+		check(node.right)
+	}
+	check(tree.root)
+}
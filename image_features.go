@@ -12,8 +12,6 @@ type RandomFeatureSelector interface {
 
 type GrayWithFeatures struct {
 	*image.Gray
-	memoSeed int32
-	memoValue float64
 	randomFeatureSelector RandomFeatureSelector
 }
 
@@ -152,53 +150,75 @@ func randomRectangle (s int32, dx, dy int) (r image.Rectangle, newS int32) {
 	return image.Rect(x1, y1, x2, y2), s
 }
 
-func (gwf *GrayWithFeatures) RandomFeature(s int32) float64 {
+func (gwf *GrayWithFeatures) RandomFeature(s int32) (result float64) {
 	// Select a random feature assuming "s" is a random 32-bit
 	// integer.  The same "s" should *always* produce the same
 	// result on the same image.  The same "s" should always
 	// select the same "feature" (same window, same attribute,
 	// etc.)  regardless of the data values.
 
-	// Use memoized lookups in case called with same "s" is used
-	// repeatedly (e.g., in sorts).
+	// RandomFeature() does not modify gwf, so it may be called
+	// concurrently by trees that are trained in parallel.
 
-	if s == gwf.memoSeed {
-		return gwf.memoValue
-	}
-	
 	dx := gwf.Rect.Dx()
 	dy := gwf.Rect.Dy()
 
 	var subRect image.Rectangle
 
 	if dx != 0 && dy != 0 {
-		gwf.memoSeed = s
 		subRect,s = randomRectangle (s, dx, dy)
 		si := gwf.SubImage(subRect).(*image.Gray)
-		subimage := &GrayWithFeatures{si,0,0.0,nil}
+		subimage := &GrayWithFeatures{Gray: si}
 		
 		switch (s%8) {
 		case 0:
-			gwf.memoValue = subimage.Mass()
+			result = subimage.Mass()
 		case 1:
-			gwf.memoValue,_ = subimage.Centroid()
+			result,_ = subimage.Centroid()
 		case 2:
-			_,gwf.memoValue = subimage.Centroid()
+			_,result = subimage.Centroid()
 		case 3:
-			gwf.memoValue,_,_ = subimage.Moments()
+			result,_,_ = subimage.Moments()
 		case 4:
-			_,gwf.memoValue,_ = subimage.Moments()
+			_,result,_ = subimage.Moments()
 		case 5:
-			_,_,gwf.memoValue = subimage.Moments()
+			_,_,result = subimage.Moments()
 		case 6:
-			gwf.memoValue,_ = subimage.Edges()
+			result,_ = subimage.Edges()
 		case 7:
 			if subimage.Rect.Dx() == 0 || subimage.Rect.Dy() == 0 {
 				fmt.Printf ("subrect = %v\n", subRect)
 				panic ("fs.rows or fs.cols is zero in RandomFeature()\n")
 			}
-			_,gwf.memoValue = subimage.Edges()
+			_,result = subimage.Edges()
 		}
 	}
-	return gwf.memoValue
+	return result
+}
+
+// grayFeatureNames names the attributes selected by RandomFeature().
+var grayFeatureNames = []string{
+	"mass",
+	"centroid-x",
+	"centroid-y",
+	"x radius of gyration",
+	"y radius of gyration",
+	"xy correlation",
+	"vertical edges",
+	"horizontal edges"}
+
+// DescribeFeature() describes the feature selected by
+// RandomFeature(s).  The description depends only on the size of the
+// image.
+func (gwf *GrayWithFeatures) DescribeFeature(s int32) string {
+	if s < 0 {
+		return describeFeature(nil, s)
+	}
+	dx := gwf.Rect.Dx()
+	dy := gwf.Rect.Dy()
+	if dx == 0 || dy == 0 {
+		return "empty image"
+	}
+	r,s := randomRectangle(s, dx, dy)
+	return fmt.Sprintf("%s of rect (%d,%d)-(%d,%d)", grayFeatureNames[s%8], r.Min.X, r.Min.Y, r.Max.X, r.Max.Y)
 }
@@ -114,7 +114,7 @@ func TestImageFeatures (t *testing.T) {
 		hf.RandomFeature(int32(s))
 	}
 
-	gf := GrayWithFeatures{img,0,0.0,nil}
+	gf := GrayWithFeatures{Gray: img}
 	fmt.Printf("img = %v\n", img)
 	fmt.Printf("gf = %v\n", gf)
 	testImageEdges(t, "Image edges", gf, 4.0/3.0, 1.0)
@@ -128,14 +128,33 @@ func TestImageFeatures (t *testing.T) {
 	subimage := img.SubImage(image.Rect(1,1,3,3)).(*image.Gray)
 	fmt.Printf ("subimage: %v\n", subimage)
 
-	gfsi := GrayWithFeatures{subimage,0,0.0,nil}
+	gfsi := GrayWithFeatures{Gray: subimage}
 	testImageEdges(t, "Image edge (1st subimage)", gfsi, 1.0, 1.0)
 	testImageCentroid(t, "Image centroid (1st subimage)", gfsi, 0.5, 0.5)
 	testImageMoments(t, "Image moments (1st subimage)", gfsi, 0.5, 0.5, 1.0)
 
 	subimage = img.SubImage(image.Rect(3,3,4,4)).(*image.Gray)
-	gfsi = GrayWithFeatures{subimage,0,0.0,nil}
+	gfsi = GrayWithFeatures{Gray: subimage}
 	testImageEdges(t, "Image edges (2nd subimage)", gfsi, 0.0, 0.0)
 	testImageCentroid(t, "Image centroid (2nd subimage)", gfsi, 0.0, 0.0)
 	testImageMoments(t, "Image moments (2nd subimage)", gfsi, 0.0, 0.0, 0.0)
 }
+
+func TestDescribeFeature (t *testing.T) {
+	img := GrayWithFeatures{Gray: image.NewGray(image.Rect(0, 0, 16, 16))}
+	// Rectangle (3,4)-(10,12) and attribute 1
+	s := int32(3 + 16*(4 + 16*(6 + 13*(7 + 12*1))))
+	if d := img.DescribeFeature(s); d != "centroid-x of rect (3,4)-(10,12)" {
+		t.Errorf ("seed %d described as \"%s\"", s, d)
+	}
+
+	hf := NewHierarchicalFeatures(image.NewGray(image.Rect(0, 0, 8, 8)))
+	// Depth 2, upper partition, then left minus right, then mass
+	s = int32(2 + 5*(0 + 6*(5 + 6*0)))
+	if d := hf.DescribeFeature(s); d != "depth-2 upper > left minus right mass" {
+		t.Errorf ("seed %d described as \"%s\"", s, d)
+	}
+	if d := hf.DescribeFeature(5*7); d != "depth-0 whole image inertia determinant" {
+		t.Errorf ("seed 35 described as \"%s\"", d)
+	}
+}
@@ -0,0 +1,152 @@
+package ML
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// FeatureImportance maps the seed of a feature (the value passed to
+// a feature selector, or categoricalSeed() of a categorical column)
+// to its importance.
+type FeatureImportance map[int32]float64
+
+// Ranked() returns the seeds in order of decreasing importance.
+func (fi FeatureImportance) Ranked() []int32 {
+	seeds := make([]int32, 0, len(fi))
+	for seed,_ := range fi {
+		seeds = append(seeds, seed)
+	}
+	sort.Slice(seeds, func(i, j int) bool {
+		if fi[seeds[i]] != fi[seeds[j]] {
+			return fi[seeds[i]] > fi[seeds[j]]
+		}
+		return seeds[i] < seeds[j]
+	})
+	return seeds
+}
+
+// importanceReporter is implemented by classifiers, such as *Tree,
+// that can report impurity-based feature importance.
+type importanceReporter interface {
+	Importance() FeatureImportance
+}
+
+// Importance() returns the mean decrease in impurity of each feature
+// the tree splits on.  A split contributes the decrease from its
+// metric to the compositeSplitMetric of its partitions, weighted by
+// the fraction of the training weight that reached it.
+func (tree *Tree) Importance() FeatureImportance {
+	importance := make(FeatureImportance)
+	if tree.root == nil {
+		return importance
+	}
+	tree.root.addImportance(importance)
+	total := tree.root.statistics.WeightedCount()
+	if total > 0 {
+		for seed,_ := range importance {
+			importance[seed] /= total
+		}
+	}
+	return importance
+}
+
+// addImportance() adds the weighted decrease in impurity at this node
+// and its descendants to "importance".
+func (tree *treeNode) addImportance(importance FeatureImportance) {
+	if tree.seed == -1 {
+		return
+	}
+	weight := tree.statistics.WeightedCount()
+	importance[tree.seed] += weight*(tree.statistics.Metric() - compositeMetric(tree.left.statistics, tree.right.statistics))
+	tree.left.addImportance(importance)
+	tree.right.addImportance(importance)
+}
+
+// Importance() returns the impurity-based importance of each feature
+// averaged over the classifiers of the ensemble that report it.
+func (te *Ensemble) Importance() FeatureImportance {
+	importance := make(FeatureImportance)
+	reporters := 0
+	for _,c := range te.classifiers {
+		if ir,ok := c.(importanceReporter); ok {
+			for seed,v := range ir.Importance() {
+				importance[seed] += v
+			}
+			reporters += 1
+		}
+	}
+	for seed,_ := range importance {
+		importance[seed] /= float64(reporters)
+	}
+	return importance
+}
+
+// predictionLoss() returns the loss of predicting "estimate" for
+// "d": 0 or 1 for categorical outputs and the squared error for
+// continuous ones.
+func predictionLoss(d *Data, estimate float64) float64 {
+	if d.outputCategories > 1 {
+		if estimate != d.output {
+			return 1.0
+		}
+		return 0.0
+	}
+	return (d.output - estimate)*(d.output - estimate)
+}
+
+// PermutationImportance() returns the out-of-bag permutation
+// importance of the features selected by "seeds": the increase in
+// each classifier's out-of-bag loss (misclassification rate or mean
+// squared error) when the values of the feature are shuffled among
+// its out-of-bag records, averaged over the classifiers.  If "seeds"
+// is nil, the features with impurity-based importance are used.
+// "data" must be the data passed to Train(); only classifiers trained
+// by Train() take part.
+func (te *Ensemble) PermutationImportance(data []*Data, seeds []int32, rng *rand.Rand) FeatureImportance {
+	if seeds == nil {
+		seeds = te.Importance().Ranked()
+	}
+	importance := make(FeatureImportance)
+	classifiers := 0
+	for i,c := range te.classifiers {
+		if i >= len(te.oob) || len(te.oob[i]) == 0 {
+			continue
+		}
+		classifiers += 1
+		oob := te.oob[i]
+
+		baseline := 0.0
+		for _,j := range oob {
+			baseline += predictionLoss(data[j], c.Classify(data[j].FeatureSelector()).Estimate())
+		}
+
+		values := make([]float64, len(oob))
+		for _,seed := range seeds {
+			for k,j := range oob {
+				values[k] = data[j].feature(seed)
+			}
+			rng.Shuffle(len(values), func(a, b int) {
+				values[a],values[b] = values[b],values[a]
+			})
+
+			permuted := 0.0
+			for k,j := range oob {
+				d := data[j]
+				value := values[k]
+				permuted += predictionLoss(d, c.Classify(func(s int32) float64 {
+					if s == seed {
+						return value
+					}
+					return d.feature(s)
+				}).Estimate())
+			}
+			importance[seed] += (permuted - baseline)/float64(len(oob))
+		}
+	}
+	for _,seed := range seeds {
+		if classifiers > 0 {
+			importance[seed] /= float64(classifiers)
+		}
+	}
+	return importance
+}
@@ -0,0 +1,71 @@
+package ML
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// importanceTestData() returns records whose output depends only on
+// the first of two features.  Even seeds select the first feature.
+func importanceTestData() []*Data {
+	rng := rand.New(rand.NewSource(5))
+	data := make([]*Data, 0)
+	for i:=0; i<200; i++ {
+		features := []float64{rng.Float64(), rng.Float64()}
+		output := 0.0
+		if features[0] > 0.5 {
+			output = 1.0
+		}
+		data = append(data, &Data{
+			continuousFeatures: features,
+			output: output,
+			outputCategories: 2,
+			weight: 1.0,
+			oobAccumulator: newVoteAccumulator(2),
+			featureSelector: func (s int32) float64 { return features[s % 2] }})
+	}
+	return data
+}
+
+// byFeature() sums importance over the seeds of each feature.
+func byFeature(importance FeatureImportance) (result [2]float64) {
+	for seed,v := range importance {
+		result[seed % 2] += v
+	}
+	return result
+}
+
+func TestImportance (t *testing.T) {
+	data := importanceTestData()
+
+	tree := NewTree(EntropyAccumulatorFactory(2))
+	tree.SetFeaturesToTry(4)
+	tree.SetRand(rand.New(rand.NewSource(1)))
+	tree.Train(data)
+	importance := byFeature(tree.Importance())
+	// The tree separates the classes, so the importances add up to
+	// the entropy of the root.
+	if total := importance[0] + importance[1]; !aboutEqual(total, tree.root.statistics.Metric()) {
+		t.Errorf ("total importance %g does not match root metric %g", total, tree.root.statistics.Metric())
+	}
+	if importance[0] <= importance[1] {
+		t.Errorf ("expected feature 0 to be more important; got %v", importance)
+	}
+
+	ensemble := NewEnsemble()
+	ensemble.SetClassifierFactory(func () Classifier {
+		tree := NewTree(EntropyAccumulatorFactory(2))
+		tree.SetFeaturesToTry(4)
+		return tree
+	})
+	ensemble.Train(data, 10, 2, 7)
+	importance = byFeature(ensemble.Importance())
+	if importance[0] <= importance[1] {
+		t.Errorf ("expected feature 0 to be more important in the ensemble; got %v", importance)
+	}
+
+	permutation := byFeature(ensemble.PermutationImportance(data, nil, rand.New(rand.NewSource(3))))
+	if permutation[0] < 0.2 || permutation[0] <= permutation[1] {
+		t.Errorf ("expected permuting feature 0 to hurt; got %v", permutation)
+	}
+}
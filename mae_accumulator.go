@@ -0,0 +1,250 @@
+package ML
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// valueHeap is a heap of float64 values.  It is a max-heap if "max"
+// is true and a min-heap otherwise.
+type valueHeap struct {
+	values []float64
+	max bool
+}
+
+func (h *valueHeap) Len() int {
+	return len(h.values)
+}
+
+func (h *valueHeap) Less(i, j int) bool {
+	if h.max {
+		return h.values[i] > h.values[j]
+	}
+	return h.values[i] < h.values[j]
+}
+
+func (h *valueHeap) Swap(i, j int) {
+	h.values[i],h.values[j] = h.values[j],h.values[i]
+}
+
+func (h *valueHeap) Push(x interface{}) {
+	h.values = append(h.values, x.(float64))
+}
+
+func (h *valueHeap) Pop() interface{} {
+	n := len(h.values)
+	x := h.values[n-1]
+	h.values = h.values[0:n-1]
+	return x
+}
+
+// medianHalf is one half of the values accumulated by an
+// MAEAccumulator.  Removed values stay in the heap until they reach
+// its top; "removed" counts them by value and "live" counts the
+// values that have not been removed.
+type medianHalf struct {
+	heap valueHeap
+	live map[float64]int
+	removed map[float64]int
+	size int
+	sum float64
+}
+
+func newMedianHalf(max bool) *medianHalf {
+	return &medianHalf{
+		heap: valueHeap{max: max},
+		live: make(map[float64]int),
+		removed: make(map[float64]int)}
+}
+
+func (mh *medianHalf) clone() *medianHalf {
+	result := newMedianHalf(mh.heap.max)
+	result.heap.values = append(result.heap.values, mh.heap.values...)
+	for x,n := range mh.live {
+		result.live[x] = n
+	}
+	for x,n := range mh.removed {
+		result.removed[x] = n
+	}
+	result.size = mh.size
+	result.sum = mh.sum
+	return result
+}
+
+func (mh *medianHalf) push(x float64) {
+	heap.Push(&mh.heap, x)
+	mh.live[x] += 1
+	mh.size += 1
+	mh.sum += x
+}
+
+// remove() removes "x", which must be live, lazily.
+func (mh *medianHalf) remove(x float64) {
+	mh.live[x] -= 1
+	if mh.live[x] == 0 {
+		delete(mh.live, x)
+	}
+	mh.removed[x] += 1
+	mh.size -= 1
+	mh.sum -= x
+	mh.prune()
+}
+
+// prune() discards removed values from the top of the heap.
+func (mh *medianHalf) prune() {
+	for len(mh.heap.values) > 0 && mh.removed[mh.heap.values[0]] > 0 {
+		x := heap.Pop(&mh.heap).(float64)
+		mh.removed[x] -= 1
+		if mh.removed[x] == 0 {
+			delete(mh.removed, x)
+		}
+	}
+}
+
+func (mh *medianHalf) top() float64 {
+	return mh.heap.values[0]
+}
+
+func (mh *medianHalf) pop() float64 {
+	x := mh.top()
+	heap.Pop(&mh.heap)
+	mh.live[x] -= 1
+	if mh.live[x] == 0 {
+		delete(mh.live, x)
+	}
+	mh.size -= 1
+	mh.sum -= x
+	mh.prune()
+	return x
+}
+
+// MAEAccumulator accumulates continuous outputs.  Its Estimate() is
+// the median and its Metric() is the mean absolute deviation from the
+// median, which makes trees robust to outliers in the output.  The
+// values are kept in two heaps, the lower half in a max-heap and the
+// upper half in a min-heap, so that Add() and Remove() take
+// logarithmic time and Metric() takes constant time.
+type MAEAccumulator struct {
+	lower, upper *medianHalf
+}
+
+func NewMAEAccumulator() *MAEAccumulator {
+	return &MAEAccumulator{
+		lower: newMedianHalf(true),
+		upper: newMedianHalf(false)}
+}
+
+func MAEAccumulatorFactory() func() CVAccumulator {
+	return func () CVAccumulator {
+		return NewMAEAccumulator()
+	}
+}
+
+func (ma *MAEAccumulator) Clone() ErrorAccumulator {
+	return &MAEAccumulator{
+		lower: ma.lower.clone(),
+		upper: ma.upper.clone()}
+}
+
+// balance() restores the invariant that the lower half has as many
+// values as the upper half or one more.
+func (ma *MAEAccumulator) balance() {
+	for ma.lower.size > ma.upper.size + 1 {
+		ma.upper.push(ma.lower.pop())
+	}
+	for ma.upper.size > ma.lower.size {
+		ma.lower.push(ma.upper.pop())
+	}
+}
+
+func (ma *MAEAccumulator) Add(x float64) {
+	if ma.lower.size == 0 || x <= ma.lower.top() {
+		ma.lower.push(x)
+	} else {
+		ma.upper.push(x)
+	}
+	ma.balance()
+}
+
+func (ma *MAEAccumulator) Remove(x float64) {
+	switch {
+	case ma.lower.live[x] > 0:
+		ma.lower.remove(x)
+	case ma.upper.live[x] > 0:
+		ma.upper.remove(x)
+	default:
+		panic(errors.New(fmt.Sprintf("Remove() of %g, which was not added", x)))
+	}
+	ma.balance()
+}
+
+// Merge() adds the values accumulated by "other", which must be an
+// *MAEAccumulator.
+func (ma *MAEAccumulator) Merge(other ErrorAccumulator) {
+	o,ok := other.(*MAEAccumulator)
+	if !ok {
+		panic(errors.New(fmt.Sprintf("Cannot merge %T into an MAEAccumulator", other)))
+	}
+	for _,x := range o.values() {
+		ma.Add(x)
+	}
+}
+
+func (ma *MAEAccumulator) Count() int {
+	return ma.lower.size + ma.upper.size
+}
+
+// Metric() returns the mean absolute deviation from the median.  Any
+// value between the two middle values minimizes the deviation, so
+// the top of the lower half is used.
+func (ma *MAEAccumulator) Metric() float64 {
+	n := ma.Count()
+	if n == 0 {
+		return 0.0
+	}
+	m := ma.lower.top()
+	result := (m*float64(ma.lower.size) - ma.lower.sum + ma.upper.sum - m*float64(ma.upper.size))/float64(n)
+	// Rounding in the running sums may leave a tiny negative value.
+	if result < 0.0 {
+		result = 0.0
+	}
+	return result
+}
+
+// Estimate() returns the median, which is the mean of the two middle
+// values if the count is even.
+func (ma *MAEAccumulator) Estimate() float64 {
+	if ma.Count() == 0 {
+		return 0.0
+	}
+	if ma.lower.size == ma.upper.size {
+		return (ma.lower.top() + ma.upper.top())/2.0
+	}
+	return ma.lower.top()
+}
+
+func (ma *MAEAccumulator) Clear() {
+	ma.lower = newMedianHalf(true)
+	ma.upper = newMedianHalf(false)
+}
+
+// values() returns the accumulated values in increasing order.
+func (ma *MAEAccumulator) values() []float64 {
+	result := make([]float64, 0, ma.Count())
+	for _,half := range []*medianHalf{ma.lower, ma.upper} {
+		for x,n := range half.live {
+			for i:=0; i<n; i++ {
+				result = append(result, x)
+			}
+		}
+	}
+	sort.Float64s(result)
+	return result
+}
+
+func (ma *MAEAccumulator) Dump(w io.Writer, indent int) {
+	fmt.Fprintf (w, "%*scount: %d, median: %g, mean absolute deviation: %g\n", indent, "", ma.Count(), ma.Estimate(), ma.Metric())
+}
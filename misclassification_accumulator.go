@@ -0,0 +1,54 @@
+package ML
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MisclassificationAccumulator accumulates categorical outputs like
+// EntropyAccumulator, but its Metric() is the misclassification
+// rate, the fraction of outputs that differ from Estimate().
+type MisclassificationAccumulator struct {
+	EntropyAccumulator
+}
+
+func NewMisclassificationAccumulator(categoryValueCount int) *MisclassificationAccumulator {
+	return &MisclassificationAccumulator{*NewEntropyAccumulator(categoryValueCount)}
+}
+
+func MisclassificationAccumulatorFactory (categoryValueCount int) func() CVAccumulator {
+	return func() CVAccumulator {
+		return NewMisclassificationAccumulator(categoryValueCount)
+	}
+}
+
+func (ma *MisclassificationAccumulator) Clone() ErrorAccumulator {
+	return &MisclassificationAccumulator{*ma.EntropyAccumulator.Clone().(*EntropyAccumulator)}
+}
+
+// Merge() adds the category counts of "other", which must be a
+// *MisclassificationAccumulator with the same number of categories.
+func (ma *MisclassificationAccumulator) Merge(other ErrorAccumulator) {
+	o,ok := other.(*MisclassificationAccumulator)
+	if !ok {
+		panic(errors.New(fmt.Sprintf("Cannot merge %T into a MisclassificationAccumulator", other)))
+	}
+	ma.mergeCounts(&o.EntropyAccumulator)
+}
+
+func (ma *MisclassificationAccumulator) Metric() float64 {
+	if ma.totalCount == 0 {
+		return 0.0
+	}
+	maxCount := 0
+	for _,count := range ma.counts {
+		if count > maxCount {
+			maxCount = count
+		}
+	}
+	return 1.0 - float64(maxCount)/float64(ma.totalCount)
+}
+
+func (ma MisclassificationAccumulator) String() string {
+	return fmt.Sprintf("misclassification%v", ma.EntropyAccumulator)
+}
@@ -0,0 +1,546 @@
+package ML
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+)
+
+// errUntrained is returned when saving or exporting a tree that has
+// not been trained.
+var errUntrained = errors.New("Cannot save or export a tree that has not been trained")
+
+// Trained trees and ensembles are saved either in a binary format
+// or as JSON.  The binary format is a four byte magic number ("MLT"
+// or "MLE" followed by a format byte), a big-endian uint32 version,
+// and a gob-encoded record.  The JSON format is a single object
+// carrying the same record along with its kind and version.  Fields
+// added to the records do not change the version: files without them
+// load with their zero values, which must mean the old behavior.
+// Only an incompatible change of format calls for a new version.
+const persistenceVersion = 1
+
+var (
+	treeMagic = [4]byte{'M', 'L', 'T', 1}
+	ensembleMagic = [4]byte{'M', 'L', 'E', 1}
+)
+
+// accumulatorRecord is the saved form of a leaf or node accumulator.
+// Only the fields that apply to Kind are used.
+type accumulatorRecord struct {
+	Kind string
+	Count int
+	WeightedCount float64 `json:",omitempty"`
+	Sum float64 `json:",omitempty"`
+	Counts []int `json:",omitempty"`
+	Weights []float64 `json:",omitempty"`
+	SumOfXLogX float64 `json:",omitempty"`
+	Values []float64 `json:",omitempty"`
+	Mean float64 `json:",omitempty"`
+	M2 float64 `json:",omitempty"`
+}
+
+type nodeRecord struct {
+	FeatureType FeatureType
+	Seed int32
+	SplitValue float64
+	LeftCategories []bool `json:",omitempty"`
+	MissingLeft bool `json:",omitempty"`
+	Statistics *accumulatorRecord
+	Left *nodeRecord `json:",omitempty"`
+	Right *nodeRecord `json:",omitempty"`
+}
+
+type treeRecord struct {
+	MaxDepth int
+	MinLeafSize int
+	FeaturesToTry int
+	// Files without the fields from MaxBins on leave them zero,
+	// which means exact splits and no further stopping rules.
+	MaxBins int `json:",omitempty"`
+	ExtraTrees bool `json:",omitempty"`
+	MinSplitSize int `json:",omitempty"`
+	MinImpurityDecrease float64 `json:",omitempty"`
+	MaxLeaves int `json:",omitempty"`
+	MinWeightFraction float64 `json:",omitempty"`
+	ErrorCount int
+	TotalCount int
+	Root *nodeRecord
+}
+
+type ensembleRecord struct {
+	Trees []*treeRecord
+}
+
+type jsonEnvelope struct {
+	Kind string `json:"kind"`
+	Version int `json:"version"`
+	Tree *treeRecord `json:"tree,omitempty"`
+	Ensemble *ensembleRecord `json:"ensemble,omitempty"`
+}
+
+// persistentAccumulator is implemented by accumulators that can be
+// saved along with a tree.
+type persistentAccumulator interface {
+	record() *accumulatorRecord
+}
+
+func (sa *StatAccumulator) record() *accumulatorRecord {
+	return &accumulatorRecord{
+		Kind: "stat",
+		Count: sa.count,
+		Mean: sa.mean,
+		M2: sa.m2}
+}
+
+func (ea *EntropyAccumulator) record() *accumulatorRecord {
+	counts := make([]int, len(ea.counts))
+	copy(counts, ea.counts)
+	return &accumulatorRecord{
+		Kind: "entropy",
+		Count: ea.totalCount,
+		Counts: counts}
+}
+
+func (ga *GiniAccumulator) record() *accumulatorRecord {
+	r := ga.EntropyAccumulator.record()
+	r.Kind = "gini"
+	return r
+}
+
+func (ma *MisclassificationAccumulator) record() *accumulatorRecord {
+	r := ma.EntropyAccumulator.record()
+	r.Kind = "misclassification"
+	return r
+}
+
+func (pa *PoissonAccumulator) record() *accumulatorRecord {
+	return &accumulatorRecord{
+		Kind: "poisson",
+		Count: pa.count,
+		Sum: pa.sum,
+		SumOfXLogX: pa.sumOfXLogX}
+}
+
+func (ma *MAEAccumulator) record() *accumulatorRecord {
+	return &accumulatorRecord{
+		Kind: "mae",
+		Count: ma.Count(),
+		Values: ma.values()}
+}
+
+func (qa *QuantileAccumulator) record() *accumulatorRecord {
+	return &accumulatorRecord{
+		Kind: "quantile",
+		Count: qa.Count(),
+		Values: qa.Values(),
+		Mean: qa.mean,
+		M2: qa.m2}
+}
+
+func (sa *WeightedStatAccumulator) record() *accumulatorRecord {
+	return &accumulatorRecord{
+		Kind: "weightedstat",
+		Count: sa.count,
+		WeightedCount: sa.weightedCount,
+		Mean: sa.mean,
+		M2: sa.m2}
+}
+
+func (ea *WeightedEntropyAccumulator) record() *accumulatorRecord {
+	weights := make([]float64, len(ea.weights))
+	copy(weights, ea.weights)
+	return &accumulatorRecord{
+		Kind: "weightedentropy",
+		Count: ea.totalCount,
+		WeightedCount: ea.totalWeight,
+		Weights: weights}
+}
+
+// saveAccumulator() returns the record of "a".  Adapted unweighted
+// accumulators are saved as the accumulator they wrap.
+func saveAccumulator(a WeightedCVAccumulator) (*accumulatorRecord, error) {
+	var inner interface{} = a
+	if ua,ok := a.(*unitWeightAccumulator); ok {
+		inner = ua.CVAccumulator
+	}
+	pa,ok := inner.(persistentAccumulator)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("Accumulator type %T cannot be saved", inner))
+	}
+	return pa.record(), nil
+}
+
+// loadAccumulator() is the inverse of saveAccumulator().  Unweighted
+// accumulators are wrapped so that they can be used by the tree.
+func loadAccumulator(r *accumulatorRecord) (WeightedCVAccumulator, error) {
+	if r == nil {
+		return nil, errors.New("Missing accumulator")
+	}
+	switch r.Kind {
+	case "stat":
+		return &unitWeightAccumulator{&StatAccumulator{
+			count: r.Count,
+			mean: r.Mean,
+			m2: r.M2}}, nil
+	case "entropy":
+		counts := make([]int, len(r.Counts))
+		copy(counts, r.Counts)
+		return &unitWeightAccumulator{&EntropyAccumulator{
+			counts: counts,
+			totalCount: r.Count}}, nil
+	case "gini":
+		ga := NewGiniAccumulator(len(r.Counts))
+		for c,count := range r.Counts {
+			for i:=0; i<count; i++ {
+				ga.Add(float64(c))
+			}
+		}
+		return &unitWeightAccumulator{ga}, nil
+	case "misclassification":
+		counts := make([]int, len(r.Counts))
+		copy(counts, r.Counts)
+		return &unitWeightAccumulator{&MisclassificationAccumulator{EntropyAccumulator{
+			counts: counts,
+			totalCount: r.Count}}}, nil
+	case "poisson":
+		return &unitWeightAccumulator{&PoissonAccumulator{
+			count: r.Count,
+			sum: r.Sum,
+			sumOfXLogX: r.SumOfXLogX}}, nil
+	case "mae":
+		ma := NewMAEAccumulator()
+		for _,x := range r.Values {
+			ma.Add(x)
+		}
+		return &unitWeightAccumulator{ma}, nil
+	case "quantile":
+		qa := NewQuantileAccumulator()
+		for _,x := range r.Values {
+			qa.Add(x)
+		}
+		// Restore the statistics exactly rather than as recomputed
+		// from the sorted values.
+		qa.mean = r.Mean
+		qa.m2 = r.M2
+		return &unitWeightAccumulator{qa}, nil
+	case "weightedstat":
+		return &WeightedStatAccumulator{
+			count: r.Count,
+			weightedCount: r.WeightedCount,
+			mean: r.Mean,
+			m2: r.M2}, nil
+	case "weightedentropy":
+		weights := make([]float64, len(r.Weights))
+		copy(weights, r.Weights)
+		return &WeightedEntropyAccumulator{
+			weights: weights,
+			totalCount: r.Count,
+			totalWeight: r.WeightedCount}, nil
+	}
+	return nil, errors.New(fmt.Sprintf("Unknown accumulator kind \"%s\"", r.Kind))
+}
+
+func (tree *treeNode) record() (*nodeRecord, error) {
+	statistics,err := saveAccumulator(tree.statistics)
+	if err != nil {
+		return nil, err
+	}
+	r := &nodeRecord{
+		FeatureType: tree.featureType,
+		Seed: tree.seed,
+		SplitValue: tree.splitValue,
+		LeftCategories: tree.leftCategories,
+		MissingLeft: tree.missingLeft,
+		Statistics: statistics}
+	if tree.seed != -1 {
+		if r.Left,err = tree.left.record(); err != nil {
+			return nil, err
+		}
+		if r.Right,err = tree.right.record(); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func loadTreeNode(r *nodeRecord) (*treeNode, error) {
+	statistics,err := loadAccumulator(r.Statistics)
+	if err != nil {
+		return nil, err
+	}
+	node := &treeNode{
+		featureType: r.FeatureType,
+		seed: r.Seed,
+		statistics: statistics,
+		splitValue: r.SplitValue,
+		leftCategories: r.LeftCategories,
+		missingLeft: r.MissingLeft}
+	if r.Seed != -1 {
+		if r.Left == nil || r.Right == nil {
+			return nil, errors.New("Split node is missing a branch")
+		}
+		if node.left,err = loadTreeNode(r.Left); err != nil {
+			return nil, err
+		}
+		if node.right,err = loadTreeNode(r.Right); err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+func (tree *Tree) record() (*treeRecord, error) {
+	if tree.root == nil {
+		return nil, errUntrained
+	}
+	root,err := tree.root.record()
+	if err != nil {
+		return nil, err
+	}
+	r := &treeRecord{
+		MaxDepth: tree.maxDepth,
+		MinLeafSize: tree.minLeafSize,
+		FeaturesToTry: tree.featuresToTry,
+		MaxBins: tree.maxBins,
+		ExtraTrees: tree.extraTrees,
+		MinSplitSize: tree.minSplitSize,
+		MinImpurityDecrease: tree.minImpurityDecrease,
+		MaxLeaves: tree.maxLeaves,
+		MinWeightFraction: tree.minWeightFraction,
+		Root: root}
+	if ea,ok := tree.errorAccumulator.(*errorAccumulator); ok {
+		r.ErrorCount = ea.errorCount
+		r.TotalCount = ea.totalCount
+	}
+	return r, nil
+}
+
+// loadTree() reconstructs a tree from its record.  The accumulator factory of the restored tree
+// produces empty accumulators of the same kind as the saved root, so
+// a loaded tree may be retrained.
+func loadTree(r *treeRecord) (*Tree, error) {
+	if r == nil || r.Root == nil {
+		return nil, errors.New("Missing tree")
+	}
+	root,err := loadTreeNode(r.Root)
+	if err != nil {
+		return nil, err
+	}
+	prototype := root.statistics.Clone().(WeightedCVAccumulator)
+	prototype.Clear()
+	_,unweighted := prototype.(*unitWeightAccumulator)
+	return &Tree{
+		root: root,
+		maxDepth: r.MaxDepth,
+		minLeafSize: r.MinLeafSize,
+		featuresToTry: r.FeaturesToTry,
+		maxBins: r.MaxBins,
+		extraTrees: r.ExtraTrees,
+		minSplitSize: r.MinSplitSize,
+		minImpurityDecrease: r.MinImpurityDecrease,
+		maxLeaves: r.MaxLeaves,
+		minWeightFraction: r.MinWeightFraction,
+		accumulatorFactory: func() WeightedCVAccumulator {
+			return prototype.Clone().(WeightedCVAccumulator)
+		},
+		errorAccumulator: &errorAccumulator{
+			totalCount: r.TotalCount,
+			errorCount: r.ErrorCount},
+		rng: rand.New(rand.NewSource(rand.Int63())),
+		weighted: !unweighted}, nil
+}
+
+func (te *Ensemble) record() (*ensembleRecord, error) {
+	r := &ensembleRecord{Trees: make([]*treeRecord, 0, len(te.classifiers))}
+	for i,c := range te.classifiers {
+		tree,ok := c.(*Tree)
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("Classifier %d (%T) cannot be saved", i, c))
+		}
+		tr,err := tree.record()
+		if err != nil {
+			return nil, err
+		}
+		r.Trees = append(r.Trees, tr)
+	}
+	return r, nil
+}
+
+func loadEnsemble(r *ensembleRecord) (*Ensemble, error) {
+	if r == nil {
+		return nil, errors.New("Missing ensemble")
+	}
+	ensemble := NewEnsemble()
+	for _,tr := range r.Trees {
+		tree,err := loadTree(tr)
+		if err != nil {
+			return nil, err
+		}
+		ensemble.AddClassifier(tree)
+	}
+	return ensemble, nil
+}
+
+func writeBinary(w io.Writer, magic [4]byte, record interface{}) error {
+	bw := bufio.NewWriter(w)
+	if _,err := bw.Write(magic[:]); err != nil {
+		return err
+	}
+	if err := binary.Write(bw, binary.BigEndian, uint32(persistenceVersion)); err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(bw).Encode(record); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// readBinary() decodes "record" from "r".
+func readBinary(r io.Reader, magic [4]byte, record interface{}) error {
+	var header [4]byte
+	if _,err := io.ReadFull(r, header[:]); err != nil {
+		return err
+	}
+	if header != magic {
+		return errors.New(fmt.Sprintf("Unrecognized format: %q", header[:]))
+	}
+	var version uint32
+	if err := binary.Read(r, binary.BigEndian, &version); err != nil {
+		return err
+	}
+	if version != persistenceVersion {
+		return errors.New(fmt.Sprintf("Unsupported format version %d", version))
+	}
+	return gob.NewDecoder(r).Decode(record)
+}
+
+func readJSON(r io.Reader, kind string) (*jsonEnvelope, error) {
+	var envelope jsonEnvelope
+	if err := json.NewDecoder(r).Decode(&envelope); err != nil {
+		return nil, err
+	}
+	if envelope.Kind != kind {
+		return nil, errors.New(fmt.Sprintf("Expected a saved %s, got \"%s\"", kind, envelope.Kind))
+	}
+	if envelope.Version != persistenceVersion {
+		return nil, errors.New(fmt.Sprintf("Unsupported format version %d", envelope.Version))
+	}
+	return &envelope, nil
+}
+
+// Save() writes the trained tree to "w" in the binary format.
+// Feature selectors are not saved; a loaded tree classifies records
+// with whatever selector is passed to Classify().
+func (tree *Tree) Save(w io.Writer) error {
+	r,err := tree.record()
+	if err != nil {
+		return err
+	}
+	return writeBinary(w, treeMagic, r)
+}
+
+// Load() replaces the tree with one previously written by Save().
+func (tree *Tree) Load(r io.Reader) error {
+	var tr treeRecord
+	if err := readBinary(r, treeMagic, &tr); err != nil {
+		return err
+	}
+	loaded,err := loadTree(&tr)
+	if err != nil {
+		return err
+	}
+	*tree = *loaded
+	return nil
+}
+
+// SaveJSON() writes the trained tree to "w" as JSON.
+func (tree *Tree) SaveJSON(w io.Writer) error {
+	r,err := tree.record()
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(w).Encode(&jsonEnvelope{Kind: "tree", Version: persistenceVersion, Tree: r})
+}
+
+// LoadJSON() replaces the tree with one previously written by SaveJSON().
+func (tree *Tree) LoadJSON(r io.Reader) error {
+	envelope,err := readJSON(r, "tree")
+	if err != nil {
+		return err
+	}
+	loaded,err := loadTree(envelope.Tree)
+	if err != nil {
+		return err
+	}
+	*tree = *loaded
+	return nil
+}
+
+// Save() writes all classifiers of the ensemble to "w" in the
+// binary format.  Every classifier must be a *Tree.
+func (te *Ensemble) Save(w io.Writer) error {
+	r,err := te.record()
+	if err != nil {
+		return err
+	}
+	return writeBinary(w, ensembleMagic, r)
+}
+
+// Load() replaces the classifiers of the ensemble with those
+// previously written by Save().
+func (te *Ensemble) Load(r io.Reader) error {
+	var er ensembleRecord
+	if err := readBinary(r, ensembleMagic, &er); err != nil {
+		return err
+	}
+	loaded,err := loadEnsemble(&er)
+	if err != nil {
+		return err
+	}
+	te.replaceClassifiers(loaded)
+	return nil
+}
+
+// replaceClassifiers() replaces the classifiers of the ensemble with
+// those of "loaded", which have no out-of-bag records, and keeps its
+// training settings.  A zero Ensemble gets the default settings.
+func (te *Ensemble) replaceClassifiers(loaded *Ensemble) {
+	te.classifiers = loaded.classifiers
+	te.oob = loaded.oob
+	if te.errorAccumulator == nil {
+		te.errorAccumulator = loaded.errorAccumulator
+	}
+	if te.sampler == nil {
+		te.sampler = loaded.sampler
+	}
+}
+
+// SaveJSON() writes all classifiers of the ensemble to "w" as JSON.
+func (te *Ensemble) SaveJSON(w io.Writer) error {
+	r,err := te.record()
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(w).Encode(&jsonEnvelope{Kind: "ensemble", Version: persistenceVersion, Ensemble: r})
+}
+
+// LoadJSON() replaces the classifiers of the ensemble with those
+// previously written by SaveJSON().
+func (te *Ensemble) LoadJSON(r io.Reader) error {
+	envelope,err := readJSON(r, "ensemble")
+	if err != nil {
+		return err
+	}
+	loaded,err := loadEnsemble(envelope.Ensemble)
+	if err != nil {
+		return err
+	}
+	te.replaceClassifiers(loaded)
+	return nil
+}
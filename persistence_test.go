@@ -0,0 +1,169 @@
+package ML
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func persistenceTestData() []*Data {
+	data := make([]*Data, 0)
+	for i:=0; i<40; i++ {
+		features := []float64{float64(i % 7), float64(i % 5), float64(i)}
+		output := 0.0
+		if features[0] > 3.0 {
+			output = 1.0
+		}
+		if features[1] > 2.0 {
+			output = 2.0
+		}
+		data = append(data, &Data{
+			continuousFeatures: features,
+			output: output,
+			weight: 1.0 + float64(i % 3),
+			featureSelector: func (s int32) float64 { return features[s % 3] }})
+	}
+	return data
+}
+
+func dumpString(tree *Tree) string {
+	var b bytes.Buffer
+	tree.Dump(&b)
+	return b.String()
+}
+
+func checkSameTree(t *testing.T, msg string, original, loaded *Tree, data []*Data) {
+	if dumpString(original) != dumpString(loaded) {
+		t.Errorf ("%s: loaded tree differs:\n%s\nexpected:\n%s", msg, dumpString(loaded), dumpString(original))
+	}
+	for _,d := range data {
+		o := original.Classify(d.featureSelector)
+		l := loaded.Classify(d.featureSelector)
+		if o.Estimate() != l.Estimate() || o.Metric() != l.Metric() || o.Count() != l.Count() {
+			t.Errorf ("%s: loaded tree classifies %v as %g; expected %g", msg, d.continuousFeatures, l.Estimate(), o.Estimate())
+		}
+	}
+}
+
+func TestTreePersistence (t *testing.T) {
+	data := persistenceTestData()
+
+	trees := []*Tree{
+		NewTree(EntropyAccumulatorFactory(3)),
+		NewTree(StatAccumulatorFactory()),
+		NewWeightedTree(WeightedEntropyAccumulatorFactory(3)),
+		NewWeightedTree(WeightedStatAccumulatorFactory()),
+		NewTree(GiniAccumulatorFactory(3)),
+		NewTree(MisclassificationAccumulatorFactory(3)),
+		NewTree(PoissonAccumulatorFactory()),
+		NewTree(MAEAccumulatorFactory()),
+		NewTree(QuantileAccumulatorFactory())}
+
+	for _,tree := range trees {
+		tree.SetFeaturesToTry(3)
+		tree.Train(data)
+
+		var b bytes.Buffer
+		if err := tree.Save(&b); err != nil {
+			t.Fatalf ("Save() failed: %v", err)
+		}
+		var loaded Tree
+		if err := loaded.Load(&b); err != nil {
+			t.Fatalf ("Load() failed: %v", err)
+		}
+		checkSameTree(t, "binary", tree, &loaded, data)
+
+		b.Reset()
+		if err := tree.SaveJSON(&b); err != nil {
+			t.Fatalf ("SaveJSON() failed: %v", err)
+		}
+		var loadedJSON Tree
+		if err := loadedJSON.LoadJSON(&b); err != nil {
+			t.Fatalf ("LoadJSON() failed: %v", err)
+		}
+		checkSameTree(t, "JSON", tree, &loadedJSON, data)
+
+		// A loaded tree can be retrained.
+		loaded.Train(data)
+	}
+
+	var tree Tree
+	if err := tree.Load(bytes.NewBufferString("not a tree")); err == nil {
+		t.Errorf ("Load() accepted bad input")
+	}
+}
+
+func TestEnsemblePersistence (t *testing.T) {
+	data := persistenceTestData()
+
+	ensemble := NewEnsemble()
+	for i:=0; i<5; i++ {
+		tree := NewTree(EntropyAccumulatorFactory(3))
+		tree.SetFeaturesToTry(2)
+		tree.Train(data)
+		ensemble.AddClassifier(tree)
+	}
+
+	var b bytes.Buffer
+	if err := ensemble.Save(&b); err != nil {
+		t.Fatalf ("Save() failed: %v", err)
+	}
+	loaded := NewEnsemble()
+	if err := loaded.Load(&b); err != nil {
+		t.Fatalf ("Load() failed: %v", err)
+	}
+
+	b.Reset()
+	if err := ensemble.SaveJSON(&b); err != nil {
+		t.Fatalf ("SaveJSON() failed: %v", err)
+	}
+	loadedJSON := NewEnsemble()
+	if err := loadedJSON.LoadJSON(&b); err != nil {
+		t.Fatalf ("LoadJSON() failed: %v", err)
+	}
+
+	if len(loaded.classifiers) != 5 || len(loadedJSON.classifiers) != 5 {
+		t.Fatalf ("expected 5 classifiers; got %d and %d", len(loaded.classifiers), len(loadedJSON.classifiers))
+	}
+	for i,c := range ensemble.classifiers {
+		checkSameTree(t, "ensemble binary", c.(*Tree), loaded.classifiers[i].(*Tree), data)
+		checkSameTree(t, "ensemble JSON", c.(*Tree), loadedJSON.classifiers[i].(*Tree), data)
+	}
+
+	// Loading keeps the training settings of the ensemble, which
+	// can then train more classifiers.
+	b.Reset()
+	if err := ensemble.Save(&b); err != nil {
+		t.Fatalf ("Save() failed: %v", err)
+	}
+	configured := NewEnsemble()
+	configured.SetClassifierFactory(func() Classifier {
+		tree := NewTree(EntropyAccumulatorFactory(3))
+		tree.SetFeaturesToTry(2)
+		return tree
+	})
+	configured.SetSampler(BootstrapSampler())
+	if err := configured.Load(&b); err != nil {
+		t.Fatalf ("Load() failed: %v", err)
+	}
+	if configured.classifierFactory == nil || configured.sampler == nil || configured.errorAccumulator == nil {
+		t.Fatalf ("Load() discarded the ensemble's settings")
+	}
+	configured.Train(data, 3, 1, 7)
+	if len(configured.classifiers) != 8 || len(configured.oob) != 8 || configured.oob[0] != nil || configured.oob[5] == nil {
+		t.Errorf ("expected 5 loaded and 3 trained classifiers; got %d and out-of-bag sets %v", len(configured.classifiers), configured.oob)
+	}
+}
+
+func TestLoadUnsupportedVersion (t *testing.T) {
+	saved := `{"kind":"tree","version":2,"tree":{"MaxDepth":-1,"MinLeafSize":1,"FeaturesToTry":1,"ErrorCount":0,"TotalCount":1,
+		"Root":{"FeatureType":0,"Seed":-1,"SplitValue":0,"Statistics":{"Kind":"stat","Count":1,"Mean":3}}}}`
+	var tree Tree
+	if err := tree.LoadJSON(strings.NewReader(saved)); err == nil || !strings.Contains(err.Error(), "version 2") {
+		t.Errorf ("expected an unsupported version error; got %v", err)
+	}
+	saved = strings.Replace(saved, `"version":2`, `"version":1`, 1)
+	if err := tree.LoadJSON(strings.NewReader(saved)); err != nil || tree.Classify(func (int32) float64 { return 0.0 }).Estimate() != 3.0 {
+		t.Errorf ("LoadJSON() of the current version failed: %v", err)
+	}
+}
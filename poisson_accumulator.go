@@ -0,0 +1,108 @@
+package ML
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// PoissonAccumulator accumulates non-negative outputs, such as
+// counts.  Its Metric() is the mean Poisson deviance about the mean,
+// (2/n) sum(y log(y/mean) - (y - mean)), which suits outputs whose
+// variance grows with their mean.  A new PoissonAccumulator may be
+// declared without initialization.
+type PoissonAccumulator struct {
+	count int
+	sum float64
+	// sumOfXLogX is sum(y log y), taking 0 log 0 as 0.
+	sumOfXLogX float64
+}
+
+func PoissonAccumulatorFactory() func() CVAccumulator {
+	return func () CVAccumulator {
+		return &PoissonAccumulator{}
+	}
+}
+
+func xLogX(x float64) float64 {
+	if x == 0.0 {
+		return 0.0
+	}
+	return x*math.Log(x)
+}
+
+func (pa *PoissonAccumulator) Clone() ErrorAccumulator {
+	result := *pa
+	return &result
+}
+
+func (pa *PoissonAccumulator) Add(x float64) {
+	if x < 0.0 {
+		panic(errors.New(fmt.Sprintf("Negative output %g added to a PoissonAccumulator", x)))
+	}
+	pa.count += 1
+	pa.sum += x
+	pa.sumOfXLogX += xLogX(x)
+}
+
+func (pa *PoissonAccumulator) Remove(x float64) {
+	if pa.count == 0 {
+		panic(errors.New("More calls to Remove() than to Add()"))
+	}
+	pa.count -= 1
+	if pa.count == 0 {
+		pa.Clear()
+		return
+	}
+	pa.sum -= x
+	pa.sumOfXLogX -= xLogX(x)
+}
+
+// Merge() adds the outputs accumulated by "other", which must be a
+// *PoissonAccumulator.
+func (pa *PoissonAccumulator) Merge(other ErrorAccumulator) {
+	o,ok := other.(*PoissonAccumulator)
+	if !ok {
+		panic(errors.New(fmt.Sprintf("Cannot merge %T into a PoissonAccumulator", other)))
+	}
+	pa.count += o.count
+	pa.sum += o.sum
+	pa.sumOfXLogX += o.sumOfXLogX
+}
+
+func (pa *PoissonAccumulator) Count() int {
+	return pa.count
+}
+
+// Metric() returns the mean Poisson deviance.  The sum of
+// (y - mean) is zero, so the deviance is
+// (2/n) (sum(y log y) - sum(y) log(mean)).
+func (pa *PoissonAccumulator) Metric() float64 {
+	if pa.count == 0 || pa.sum <= 0.0 {
+		return 0.0
+	}
+	result := 2.0*(pa.sumOfXLogX - xLogX(pa.sum) + pa.sum*math.Log(float64(pa.count)))/float64(pa.count)
+	// Rounding may leave a tiny negative deviance.
+	if result < 0.0 {
+		result = 0.0
+	}
+	return result
+}
+
+func (pa *PoissonAccumulator) Estimate() float64 {
+	if pa.count == 0 {
+		return 0.0
+	}
+	return pa.sum/float64(pa.count)
+}
+
+func (pa *PoissonAccumulator) Clear() {
+	pa.count = 0
+	pa.sum = 0.0
+	pa.sumOfXLogX = 0.0
+}
+
+func (pa *PoissonAccumulator) Dump(w io.Writer, indent int) {
+	fmt.Fprintf (w, "%*scount: %d, sum(x): %g, sum(x log x): %g\n", indent, "", pa.count, pa.sum, pa.sumOfXLogX)
+}
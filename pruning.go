@@ -0,0 +1,247 @@
+package ML
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Minimal cost-complexity pruning.  The cost of a subtree for a
+// complexity parameter alpha is its risk plus alpha times its number
+// of leaves, where the risk of a leaf is its metric weighted by the
+// fraction of the training weight that reached it.  As alpha grows,
+// the subtree of least cost loses its weakest links: the splits whose
+// decrease in risk per leaf removed is smallest.
+
+// collapseAlphas() returns, for each split of the tree, the smallest
+// alpha for which the subtree of least cost makes it a leaf.
+func (tree *Tree) collapseAlphas() map[*treeNode]float64 {
+	collapsed := make(map[*treeNode]float64)
+	if tree.root == nil {
+		return collapsed
+	}
+	total := tree.root.statistics.WeightedCount()
+	if total <= 0.0 {
+		total = 1.0
+	}
+
+	previous := 0.0
+	for tree.root.seed != -1 {
+		if _,ok := collapsed[tree.root]; ok {
+			break
+		}
+		// The decrease in risk per leaf removed of each split of the
+		// current subtree
+		links := make(map[*treeNode]float64)
+		tree.root.weakestLinks(collapsed, total, links)
+		weakest := math.Inf(1)
+		for _,g := range links {
+			weakest = math.Min(weakest, g)
+		}
+		// Rounding may make a link weaker than one already cut.
+		alpha := math.Max(weakest, previous)
+		for node,g := range links {
+			if g <= weakest + 1.0e-12*math.Abs(weakest) {
+				collapsed[node] = alpha
+			}
+		}
+		previous = alpha
+	}
+	return collapsed
+}
+
+// weakestLinks() returns the risk and number of leaves of the subtree
+// rooted at "tree", in which the nodes in "collapsed" are leaves, and
+// adds the decrease in risk per leaf removed of each of its splits
+// to "links".
+func (tree *treeNode) weakestLinks(collapsed map[*treeNode]float64, total float64, links map[*treeNode]float64) (risk float64, leaves int) {
+	nodeRisk := tree.statistics.WeightedCount()*tree.statistics.Metric()/total
+	if _,ok := collapsed[tree]; ok || tree.seed == -1 {
+		return nodeRisk, 1
+	}
+	leftRisk, leftLeaves := tree.left.weakestLinks(collapsed, total, links)
+	rightRisk, rightLeaves := tree.right.weakestLinks(collapsed, total, links)
+	risk, leaves = leftRisk + rightRisk, leftLeaves + rightLeaves
+	links[tree] = (nodeRisk - risk)/float64(leaves - 1)
+	return risk, leaves
+}
+
+// PruningPath() returns the increasing values of alpha at which the
+// subtree of least cost changes, starting with 0, and the risk of the
+// subtree for each.  The last subtree is the root alone.  It panics
+// if the tree has not been trained.
+func (tree *Tree) PruningPath() (alphas, risks []float64) {
+	tree.requireTrained("PruningPath()")
+	collapsed := tree.collapseAlphas()
+	alphas = []float64{0.0}
+	for _,alpha := range collapsed {
+		alphas = append(alphas, alpha)
+	}
+	sort.Float64s(alphas)
+	distinct := alphas[0:1]
+	for _,alpha := range alphas[1:] {
+		if alpha != distinct[len(distinct)-1] {
+			distinct = append(distinct, alpha)
+		}
+	}
+	alphas = distinct
+
+	total := tree.root.statistics.WeightedCount()
+	if total <= 0.0 {
+		total = 1.0
+	}
+	risks = make([]float64, len(alphas))
+	for i,alpha := range alphas {
+		risks[i] = tree.root.prunedRisk(collapsed, alpha, total)
+	}
+	return alphas, risks
+}
+
+// isPrunedLeaf() returns true if "tree" is a leaf of the subtree of
+// least cost for "alpha".
+func (tree *treeNode) isPrunedLeaf(collapsed map[*treeNode]float64, alpha float64) bool {
+	if tree.seed == -1 {
+		return true
+	}
+	collapseAlpha,ok := collapsed[tree]
+	return ok && collapseAlpha <= alpha
+}
+
+func (tree *treeNode) prunedRisk(collapsed map[*treeNode]float64, alpha, total float64) float64 {
+	if tree.isPrunedLeaf(collapsed, alpha) {
+		return tree.statistics.WeightedCount()*tree.statistics.Metric()/total
+	}
+	return tree.left.prunedRisk(collapsed, alpha, total) + tree.right.prunedRisk(collapsed, alpha, total)
+}
+
+func (tree *treeNode) classifyPruned(featureSelector func(int32) float64, collapsed map[*treeNode]float64, alpha float64) WeightedCVAccumulator {
+	if tree.isPrunedLeaf(collapsed, alpha) {
+		return tree.statistics
+	}
+	if tree.goesLeft(featureSelector(tree.seed)) {
+		return tree.left.classifyPruned(featureSelector, collapsed, alpha)
+	}
+	return tree.right.classifyPruned(featureSelector, collapsed, alpha)
+}
+
+// Prune() replaces the tree by its subtree of least cost for
+// "alpha".  Pruning with the alphas of PruningPath() in increasing
+// order gives a sequence of nested subtrees.
+func (tree *Tree) Prune(alpha float64) {
+	if tree.root == nil {
+		return
+	}
+	tree.root.prune(tree.collapseAlphas(), alpha)
+}
+
+func (tree *treeNode) prune(collapsed map[*treeNode]float64, alpha float64) {
+	if tree.seed == -1 {
+		return
+	}
+	if tree.isPrunedLeaf(collapsed, alpha) {
+		*tree = *NewTreeNode(tree.statistics)
+		return
+	}
+	tree.left.prune(collapsed, alpha)
+	tree.right.prune(collapsed, alpha)
+}
+
+// pruningLosses() returns the total predictionLoss() over "data" of
+// the subtree of least cost for each of "alphas".
+func (tree *Tree) pruningLosses(data []*Data, alphas []float64) []float64 {
+	collapsed := tree.collapseAlphas()
+	losses := make([]float64, len(alphas))
+	for _,d := range data {
+		for i,alpha := range alphas {
+			losses[i] += predictionLoss(d, tree.root.classifyPruned(d.FeatureSelector(), collapsed, alpha).Estimate())
+		}
+	}
+	return losses
+}
+
+// smallestLoss() returns the index of the smallest of "losses",
+// preferring later, simpler, subtrees in case of a tie.
+func smallestLoss(losses []float64) int {
+	best := 0
+	for i,loss := range losses {
+		if loss <= losses[best] {
+			best = i
+		}
+	}
+	return best
+}
+
+// SelectAlpha() returns the alpha of PruningPath() whose subtree has
+// the smallest loss (misclassification rate or mean squared error)
+// on the held-out records "validation".  It panics if the tree has
+// not been trained.
+func (tree *Tree) SelectAlpha(validation []*Data) float64 {
+	tree.requireTrained("SelectAlpha()")
+	alphas,_ := tree.PruningPath()
+	return alphas[smallestLoss(tree.pruningLosses(validation, alphas))]
+}
+
+// CrossValidateAlpha() returns the alpha of PruningPath() whose
+// subtree has the smallest "folds"-fold cross-validated loss.  "data"
+// must be the data on which the tree was trained.  A tree with the
+// same settings is grown on each fold's complement, and is pruned at
+// the geometric mean of each alpha of the path and the next, which
+// stands for the interval in which the subtree of the whole tree
+// does not change.  "rng" assigns the records to folds.  It panics
+// if the tree has not been trained.
+func (tree *Tree) CrossValidateAlpha(data []*Data, folds int, rng *rand.Rand) float64 {
+	tree.requireTrained("CrossValidateAlpha()")
+	if folds < 2 || folds > len(data) {
+		panic(errors.New(fmt.Sprintf("Cannot cross-validate %d records with %d folds", len(data), folds)))
+	}
+	alphas,_ := tree.PruningPath()
+	representatives := make([]float64, len(alphas))
+	for i,alpha := range alphas {
+		if i+1 < len(alphas) {
+			representatives[i] = math.Sqrt(alpha*alphas[i+1])
+		} else {
+			representatives[i] = math.Inf(1)
+		}
+	}
+
+	order := rng.Perm(len(data))
+	losses := make([]float64, len(alphas))
+	for fold:=0; fold<folds; fold++ {
+		train := make([]*Data, 0, len(data))
+		test := make([]*Data, 0, len(data)/folds + 1)
+		for i,j := range order {
+			if i % folds == fold {
+				test = append(test, data[j])
+			} else {
+				train = append(train, data[j])
+			}
+		}
+		foldTree := tree.untrainedCopy(rng)
+		foldTree.Train(train)
+		for i,loss := range foldTree.pruningLosses(test, representatives) {
+			losses[i] += loss
+		}
+	}
+	return alphas[smallestLoss(losses)]
+}
+
+// requireTrained() panics if the tree has not been trained.  "caller"
+// names the method in the message.
+func (tree *Tree) requireTrained(caller string) {
+	if tree.root == nil {
+		panic(errors.New(fmt.Sprintf("%s used before Train()", caller)))
+	}
+}
+
+// untrainedCopy() returns a tree with the settings of "tree" and a
+// random source seeded from "rng".
+func (tree *Tree) untrainedCopy(rng *rand.Rand) *Tree {
+	result := *tree
+	result.root = nil
+	result.binEdges = nil
+	result.errorAccumulator = &errorAccumulator{}
+	result.rng = rand.New(rand.NewSource(rng.Int63()))
+	return &result
+}
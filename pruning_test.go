@@ -0,0 +1,116 @@
+package ML
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// pruningTestTree() returns a fully grown regression tree on noisy
+// data whose signal has only two leaves.
+func pruningTestTree(train []*Data) *Tree {
+	tree := NewTree(StatAccumulatorFactory())
+	tree.SetRand(rand.New(rand.NewSource(5)))
+	tree.Train(append([]*Data(nil), train...))
+	return tree
+}
+
+func pruningTestData(n int, rng *rand.Rand) []*Data {
+	return boostingTestData(n, rng, func(x []float64) float64 {
+		if x[0] < 0.0 {
+			return rng.NormFloat64()
+		}
+		return 4.0 + rng.NormFloat64()
+	})
+}
+
+func TestPruningPath (t *testing.T) {
+	rng := rand.New(rand.NewSource(4))
+	train := pruningTestData(200, rng)
+	tree := pruningTestTree(train)
+	alphas, risks := tree.PruningPath()
+	if len(alphas) < 3 || len(alphas) != len(risks) || alphas[0] != 0.0 {
+		t.Fatalf ("bad pruning path %v %v", alphas, risks)
+	}
+	for i:=1; i<len(alphas); i++ {
+		if alphas[i] <= alphas[i-1] || risks[i] < risks[i-1] {
+			t.Errorf ("path not increasing at %d: %v %v", i, alphas, risks)
+		}
+	}
+	root := tree.root.statistics
+	if !closeTo(risks[len(risks)-1], root.Metric()) {
+		t.Errorf ("risk of the root alone is %g, expected %g", risks[len(risks)-1], root.Metric())
+	}
+
+	// Pruning at each alpha gives nested subtrees whose risk is on
+	// the path, ending with the root alone.
+	leaves := tree.Leaves()
+	for i,alpha := range alphas {
+		pruned := pruningTestTree(train)
+		pruned.Prune(alpha)
+		if pruned.Leaves() > leaves {
+			t.Errorf ("pruning at %g gives %d leaves, more than %d", alpha, pruned.Leaves(), leaves)
+		}
+		leaves = pruned.Leaves()
+		_, prunedRisks := pruned.PruningPath()
+		if !closeTo(prunedRisks[0], risks[i]) {
+			t.Errorf ("risk after pruning at %g is %g, expected %g", alpha, prunedRisks[0], risks[i])
+		}
+	}
+	if leaves != 1 {
+		t.Errorf ("pruning at the last alpha leaves %d leaves", leaves)
+	}
+}
+
+func TestPruningUntrained (t *testing.T) {
+	data := pruningTestData(20, rand.New(rand.NewSource(12)))
+	tree := NewTree(StatAccumulatorFactory())
+	for name,f := range map[string]func(){
+		"PruningPath": func() { tree.PruningPath() },
+		"SelectAlpha": func() { tree.SelectAlpha(data) },
+		"CrossValidateAlpha": func() { tree.CrossValidateAlpha(data, 5, rand.New(rand.NewSource(13))) }} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "before Train()") {
+					t.Errorf ("%s() of an untrained tree panicked with %v", name, r)
+				}
+			}()
+			f()
+		}()
+	}
+	// Pruning an untrained tree does nothing.
+	tree.Prune(1.0)
+}
+
+func TestSelectAlpha (t *testing.T) {
+	rng := rand.New(rand.NewSource(6))
+	train := pruningTestData(300, rng)
+	validation := pruningTestData(300, rng)
+	test := pruningTestData(1000, rng)
+
+	meanLoss := func(tree *Tree) float64 {
+		loss := 0.0
+		for _,d := range test {
+			loss += predictionLoss(d, tree.Classify(d.featureSelector).Estimate())
+		}
+		return loss/float64(len(test))
+	}
+
+	full := pruningTestTree(train)
+	fullLoss := meanLoss(full)
+	for name,alpha := range map[string]float64{
+		"held-out": full.SelectAlpha(validation),
+		"cross-validated": full.CrossValidateAlpha(train, 5, rand.New(rand.NewSource(7)))} {
+		pruned := pruningTestTree(train)
+		pruned.Prune(alpha)
+		if pruned.Leaves() >= full.Leaves()/4 {
+			t.Errorf ("%s alpha %g leaves %d of %d leaves", name, alpha, pruned.Leaves(), full.Leaves())
+		}
+		// The noise variance is 1.
+		if loss := meanLoss(pruned); loss >= fullLoss || loss > 1.3 || math.IsNaN(loss) {
+			t.Errorf ("%s pruning has test loss %g, full tree %g", name, loss, fullLoss)
+		}
+	}
+}
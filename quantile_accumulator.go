@@ -0,0 +1,151 @@
+package ML
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// QuantileAccumulator accumulates continuous outputs like
+// StatAccumulator, whose Metric() and Estimate() it shares, but also
+// retains every output so that trees built with it can report the
+// distribution of the outputs at each leaf (see Quantile() and
+// Ensemble.Quantiles()).  Outputs are kept as a count per distinct
+// value.
+type QuantileAccumulator struct {
+	StatAccumulator
+	counts map[float64]int
+}
+
+func NewQuantileAccumulator() *QuantileAccumulator {
+	return &QuantileAccumulator{counts: make(map[float64]int)}
+}
+
+func QuantileAccumulatorFactory() func() CVAccumulator {
+	return func () CVAccumulator {
+		return NewQuantileAccumulator()
+	}
+}
+
+func (qa *QuantileAccumulator) Clone() ErrorAccumulator {
+	counts := make(map[float64]int, len(qa.counts))
+	for x,n := range qa.counts {
+		counts[x] = n
+	}
+	return &QuantileAccumulator{
+		StatAccumulator: *qa.StatAccumulator.Clone().(*StatAccumulator),
+		counts: counts}
+}
+
+func (qa *QuantileAccumulator) Add(x float64) {
+	qa.StatAccumulator.Add(x)
+	qa.counts[x] += 1
+}
+
+func (qa *QuantileAccumulator) Remove(x float64) {
+	if qa.counts[x] == 0 {
+		panic(errors.New(fmt.Sprintf("Remove() of %g, which was not added", x)))
+	}
+	qa.StatAccumulator.Remove(x)
+	qa.counts[x] -= 1
+	if qa.counts[x] == 0 {
+		delete(qa.counts, x)
+	}
+}
+
+func (qa *QuantileAccumulator) Clear() {
+	qa.StatAccumulator.Clear()
+	qa.counts = make(map[float64]int)
+}
+
+// Values() returns the accumulated outputs in increasing order.
+func (qa *QuantileAccumulator) Values() []float64 {
+	result := make([]float64, 0, qa.Count())
+	for _,x := range qa.distinctValues() {
+		for i:=0; i<qa.counts[x]; i++ {
+			result = append(result, x)
+		}
+	}
+	return result
+}
+
+func (qa *QuantileAccumulator) distinctValues() []float64 {
+	result := make([]float64, 0, len(qa.counts))
+	for x,_ := range qa.counts {
+		result = append(result, x)
+	}
+	sort.Float64s(result)
+	return result
+}
+
+// Quantile() returns the "q" quantile (0 <= q <= 1) of the
+// accumulated outputs: the smallest output such that a fraction of
+// at least "q" of the outputs are less than or equal to it.
+func (qa *QuantileAccumulator) Quantile(q float64) float64 {
+	distribution := make(weightedValues, 0, len(qa.counts))
+	for x,n := range qa.counts {
+		distribution = append(distribution, weightedValue{x, float64(n)})
+	}
+	return distribution.quantiles([]float64{q})[0]
+}
+
+func (qa *QuantileAccumulator) Dump(w io.Writer, indent int) {
+	fmt.Fprintf (w, "%*scount: %d, mean: %g, median: %g\n", indent, "", qa.Count(), qa.Estimate(), qa.Quantile(0.5))
+}
+
+// weightedValue is one point of an empirical distribution.
+type weightedValue struct {
+	value float64
+	weight float64
+}
+
+type weightedValues []weightedValue
+
+// quantiles() returns the quantiles "qs" of the distribution in
+// which each value has probability proportional to its weight.  The
+// q quantile is the smallest value whose cumulative probability is
+// at least q.  "wv" is sorted.  The result is 0 for an empty
+// distribution.
+func (wv weightedValues) quantiles(qs []float64) []float64 {
+	result := make([]float64, len(qs))
+	if len(wv) == 0 {
+		return result
+	}
+	sort.Slice(wv, func(i, j int) bool {
+		return wv[i].value < wv[j].value
+	})
+	total := 0.0
+	for _,v := range wv {
+		total += v.weight
+	}
+	for i,q := range qs {
+		if q < 0.0 || q > 1.0 {
+			panic(errors.New(fmt.Sprintf("Quantile %g is not in [0,1]", q)))
+		}
+		cumulative := 0.0
+		result[i] = wv[len(wv)-1].value
+		for _,v := range wv {
+			cumulative += v.weight
+			// Allow for rounding in the cumulative sum.
+			if cumulative >= q*total*(1.0 - 1.0e-12) {
+				result[i] = v.value
+				break
+			}
+		}
+	}
+	return result
+}
+
+// Merge() adds the outputs retained by "other", which must be a
+// *QuantileAccumulator, as if they had been added to "qa".
+func (qa *QuantileAccumulator) Merge(other ErrorAccumulator) {
+	o,ok := other.(*QuantileAccumulator)
+	if !ok {
+		panic(errors.New(fmt.Sprintf("Cannot merge %T into a QuantileAccumulator", other)))
+	}
+	qa.StatAccumulator.Merge(&o.StatAccumulator)
+	for x,n := range o.counts {
+		qa.counts[x] += n
+	}
+}
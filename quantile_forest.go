@@ -0,0 +1,46 @@
+package ML
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Quantiles() returns the quantiles "qs" (each between 0 and 1) of
+// the conditional distribution of the output given the features
+// selected by "featureSelector", estimated as in quantile regression
+// forests: each classifier contributes the training outputs at the
+// leaf reached by the record, each weighted by one over the number
+// of outputs at the leaf.  The classifiers must be trees built with
+// QuantileAccumulatorFactory().
+func (te *Ensemble) Quantiles(featureSelector func(int32) float64, qs []float64) []float64 {
+	pooled := make(map[float64]float64)
+	for i,c := range te.classifiers {
+		qa,ok := innerAccumulator(c.Classify(featureSelector)).(*QuantileAccumulator)
+		if !ok {
+			panic(errors.New(fmt.Sprintf("Classifier %d does not retain its training outputs", i)))
+		}
+		n := float64(qa.Count())
+		for x,count := range qa.counts {
+			pooled[x] += float64(count)/n
+		}
+	}
+	distribution := make(weightedValues, 0, len(pooled))
+	for x,w := range pooled {
+		distribution = append(distribution, weightedValue{x, w})
+	}
+	return distribution.quantiles(qs)
+}
+
+// Quantile() returns the single quantile "q" (see Quantiles()).
+func (te *Ensemble) Quantile(featureSelector func(int32) float64, q float64) float64 {
+	return te.Quantiles(featureSelector, []float64{q})[0]
+}
+
+// PredictionInterval() returns an interval expected to contain the
+// output of the record selected by "featureSelector" with
+// probability "coverage", such as 0.9, bounded by the (1-coverage)/2
+// and (1+coverage)/2 quantiles (see Quantiles()).
+func (te *Ensemble) PredictionInterval(featureSelector func(int32) float64, coverage float64) (lower, upper float64) {
+	q := te.Quantiles(featureSelector, []float64{(1.0 - coverage)/2.0, (1.0 + coverage)/2.0})
+	return q[0], q[1]
+}
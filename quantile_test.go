@@ -0,0 +1,66 @@
+package ML
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestQuantileAccumulator (t *testing.T) {
+	qa := NewQuantileAccumulator()
+	for _,x := range []float64{5, 3, 9, 1, 7, 3} {
+		qa.Add(x)
+	}
+	qa.Remove(9)
+	// 1 3 3 5 7
+	expected := map[float64]float64{0.0: 1, 0.2: 1, 0.5: 3, 0.6: 3, 0.61: 5, 1.0: 7}
+	for q,e := range expected {
+		if v := qa.Quantile(q); v != e {
+			t.Errorf ("quantile %g is %g; expected %g", q, v, e)
+		}
+	}
+	if !closeTo(qa.Estimate(), 3.8) || qa.Count() != 5 {
+		t.Errorf ("expected mean 3.8 of 5 values; got %g of %d", qa.Estimate(), qa.Count())
+	}
+	clone := qa.Clone().(*QuantileAccumulator)
+	qa.Clear()
+	if len(clone.Values()) != 5 || clone.Values()[2] != 3 || qa.Count() != 0 {
+		t.Errorf ("Clear() affected the clone: %v", clone.Values())
+	}
+}
+
+func TestQuantileForest (t *testing.T) {
+	// The output is 0..9 if the feature is 0 and 100, 110, ..., 190
+	// if it is 1.
+	data := make([]*Data, 0)
+	for i:=0; i<20; i++ {
+		x := float64(i / 10)
+		output := float64(i % 10)
+		if x == 1.0 {
+			output = 100.0 + 10.0*output
+		}
+		data = append(data, &Data{output: output, featureSelector: func (int32) float64 {return x}})
+	}
+
+	ensemble := NewEnsemble()
+	for i:=0; i<5; i++ {
+		tree := NewTree(QuantileAccumulatorFactory())
+		tree.SetRand(rand.New(rand.NewSource(int64(i))))
+		tree.Train(data)
+		ensemble.AddClassifier(tree)
+	}
+
+	zero := func (int32) float64 {return 0.0}
+	one := func (int32) float64 {return 1.0}
+	if median := ensemble.Quantile(zero, 0.5); median != 4.0 {
+		t.Errorf ("median for 0 is %g; expected 4", median)
+	}
+	if lower,upper := ensemble.PredictionInterval(zero, 0.8); lower != 0.0 || upper != 8.0 {
+		t.Errorf ("80%% interval for 0 is [%g,%g]; expected [0,8]", lower, upper)
+	}
+	if lower,upper := ensemble.PredictionInterval(one, 0.8); lower != 100.0 || upper != 180.0 {
+		t.Errorf ("80%% interval for 1 is [%g,%g]; expected [100,180]", lower, upper)
+	}
+	if estimate,_ := ensemble.Predict(one); estimate != 145.0 {
+		t.Errorf ("mean for 1 is %g; expected 145", estimate)
+	}
+}
@@ -0,0 +1,115 @@
+package ML
+
+import (
+	"math"
+	"math/rand"
+)
+
+// A Sampler draws a bag of training records from "data".  It returns
+// the indices of the records in the bag, in which an index may
+// appear more than once, and the indices of the out-of-bag records,
+// which are the records that do not appear in the bag at all.
+type Sampler func(data []*Data, rng *rand.Rand) (bag, oob []int)
+
+// outOfBag() returns, in increasing order, the indices of the "n"
+// records that do not appear in "bag".
+func outOfBag(n int, bag []int) []int {
+	inBag := make([]bool, n)
+	for _,i := range bag {
+		inBag[i] = true
+	}
+	oob := make([]int, 0)
+	for i,b := range inBag {
+		if !b {
+			oob = append(oob, i)
+		}
+	}
+	return oob
+}
+
+// sampleSize() returns "fraction" of "n", rounded to the nearest
+// integer.
+func sampleSize(n int, fraction float64) int {
+	return int(math.Floor(fraction*float64(n) + 0.5))
+}
+
+// draw() appends "size" indices drawn from "indices" to "bag".  The
+// draws are with replacement if "replacement" is true.  Otherwise
+// "size" is limited to len(indices) and "indices" is reordered.
+func draw(bag []int, indices []int, size int, replacement bool, rng *rand.Rand) []int {
+	if replacement {
+		for i:=0; i<size; i++ {
+			bag = append(bag, indices[rng.Intn(len(indices))])
+		}
+		return bag
+	}
+	if size > len(indices) {
+		size = len(indices)
+	}
+	// Partial Fisher-Yates shuffle
+	for i:=0; i<size; i++ {
+		j := i + rng.Intn(len(indices)-i)
+		indices[i],indices[j] = indices[j],indices[i]
+	}
+	return append(bag, indices[0:size]...)
+}
+
+func allIndices(n int) []int {
+	indices := make([]int, n)
+	for i,_ := range indices {
+		indices[i] = i
+	}
+	return indices
+}
+
+// BootstrapSampler() returns a Sampler that draws as many records as
+// there are in the data, with replacement.  On average about 37% of
+// the records are out of bag.
+func BootstrapSampler() Sampler {
+	return func(data []*Data, rng *rand.Rand) (bag, oob []int) {
+		bag = draw(make([]int, 0, len(data)), allIndices(len(data)), len(data), true, rng)
+		return bag, outOfBag(len(data), bag)
+	}
+}
+
+// SubsampleSampler() returns a Sampler that draws "fraction" of the
+// records without replacement.
+func SubsampleSampler(fraction float64) Sampler {
+	return func(data []*Data, rng *rand.Rand) (bag, oob []int) {
+		size := sampleSize(len(data), fraction)
+		bag = draw(make([]int, 0, size), allIndices(len(data)), size, false, rng)
+		return bag, outOfBag(len(data), bag)
+	}
+}
+
+// StratifiedSampler() returns a Sampler that draws "fraction" of the
+// records of each output category separately, so that the bag has
+// the same class proportions as the data.  Draws are with
+// replacement if "replacement" is true.  A fraction of 1.0 with
+// replacement is a stratified bootstrap.  Records with a continuous
+// output form a single stratum.
+func StratifiedSampler(fraction float64, replacement bool) Sampler {
+	return func(data []*Data, rng *rand.Rand) (bag, oob []int) {
+		strata := make(map[int][]int)
+		classes := make([]int, 0)
+		for i,d := range data {
+			class := 0
+			if d.outputCategories > 1 {
+				class = int(d.output)
+			}
+			if _,ok := strata[class]; !ok {
+				classes = append(classes, class)
+			}
+			strata[class] = append(strata[class], i)
+		}
+
+		// Visit the strata in order of first appearance so that the
+		// bag depends only on "rng".
+		bag = make([]int, 0, sampleSize(len(data), fraction))
+		for _,class := range classes {
+			indices := strata[class]
+			bag = draw(bag, indices, sampleSize(len(indices), fraction), replacement, rng)
+		}
+		return bag, outOfBag(len(data), bag)
+	}
+}
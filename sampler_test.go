@@ -0,0 +1,65 @@
+package ML
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func samplerTestData() []*Data {
+	data := make([]*Data, 0)
+	for i:=0; i<90; i++ {
+		// Two thirds of the records are category 0.
+		output := 0.0
+		if i % 3 == 0 {
+			output = 1.0
+		}
+		data = append(data, &Data{output: output, outputCategories: 2})
+	}
+	return data
+}
+
+func checkBag(t *testing.T, msg string, n int, bag, oob []int, bagSize int) {
+	if len(bag) != bagSize {
+		t.Errorf ("%s: bag size is %d; expected %d", msg, len(bag), bagSize)
+	}
+	inBag := make(map[int]bool)
+	for _,i := range bag {
+		inBag[i] = true
+	}
+	for _,i := range oob {
+		if inBag[i] {
+			t.Errorf ("%s: record %d is both in the bag and out of bag", msg, i)
+		}
+	}
+	if len(inBag) + len(oob) != n {
+		t.Errorf ("%s: %d distinct records in bag and %d out of bag; expected %d in total", msg, len(inBag), len(oob), n)
+	}
+}
+
+func TestSamplers (t *testing.T) {
+	data := samplerTestData()
+	rng := rand.New(rand.NewSource(1))
+
+	bag,oob := SubsampleSampler(2.0/3.0)(data, rng)
+	checkBag(t, "subsample", len(data), bag, oob, 60)
+
+	bag,oob = BootstrapSampler()(data, rng)
+	checkBag(t, "bootstrap", len(data), bag, oob, 90)
+	if len(oob) == 0 || len(oob) == len(data) {
+		t.Errorf ("bootstrap: implausible out-of-bag size %d", len(oob))
+	}
+
+	for _,replacement := range []bool{false, true} {
+		bag,oob = StratifiedSampler(0.5, replacement)(data, rng)
+		checkBag(t, "stratified", len(data), bag, oob, 45)
+		ones := 0
+		for _,i := range bag {
+			if data[i].output == 1.0 {
+				ones += 1
+			}
+		}
+		if ones != 15 {
+			t.Errorf ("stratified: %d records of category 1 in bag; expected 15", ones)
+		}
+	}
+}
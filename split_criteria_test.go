@@ -0,0 +1,150 @@
+package ML
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func closeTo(x, y float64) bool {
+	return math.Abs(x-y) <= 1.0e-9*(1.0 + math.Abs(y))
+}
+
+func TestGiniAndMisclassification (t *testing.T) {
+	gini := NewGiniAccumulator(3)
+	misclassification := NewMisclassificationAccumulator(3)
+	for _,c := range []float64{0, 0, 1, 2, 2, 2} {
+		gini.Add(c)
+		misclassification.Add(c)
+	}
+	// p = 1/3, 1/6, 1/2
+	if !closeTo(gini.Metric(), 1.0 - (4.0 + 1.0 + 9.0)/36.0) {
+		t.Errorf ("Gini impurity is %g; expected %g", gini.Metric(), 1.0 - 14.0/36.0)
+	}
+	if !closeTo(misclassification.Metric(), 0.5) || misclassification.Estimate() != 2.0 {
+		t.Errorf ("misclassification rate is %g with estimate %g; expected 0.5 and 2", misclassification.Metric(), misclassification.Estimate())
+	}
+
+	gini.Remove(2)
+	gini.Remove(0)
+	clone := gini.Clone().(*GiniAccumulator)
+	// Counts 1, 1, 2
+	if !closeTo(clone.Metric(), 1.0 - 6.0/16.0) || clone.Count() != 4 {
+		t.Errorf ("Gini impurity after Remove() is %g; expected %g", clone.Metric(), 1.0 - 6.0/16.0)
+	}
+	gini.Clear()
+	if gini.Metric() != 0.0 || clone.Count() != 4 {
+		t.Errorf ("Clear() affected the clone or left impurity %g", gini.Metric())
+	}
+	if outputCategories(clone) != 3 {
+		t.Errorf ("expected the Gini accumulator to be categorical")
+	}
+}
+
+func TestPoissonAccumulator (t *testing.T) {
+	values := []float64{0, 1, 3, 4, 7}
+	var pa PoissonAccumulator
+	for _,v := range values {
+		pa.Add(v)
+	}
+	mean := 3.0
+	deviance := 0.0
+	for _,y := range values {
+		deviance += 2.0*(xLogX(y) - y*math.Log(mean) - (y - mean))
+	}
+	deviance /= float64(len(values))
+	if !closeTo(pa.Metric(), deviance) || pa.Estimate() != mean {
+		t.Errorf ("Poisson deviance is %g with mean %g; expected %g and %g", pa.Metric(), pa.Estimate(), deviance, mean)
+	}
+	for _,v := range values[1:] {
+		pa.Remove(v)
+	}
+	if pa.Metric() != 0.0 || pa.Estimate() != 0.0 {
+		t.Errorf ("expected zero deviance for a single zero; got %g", pa.Metric())
+	}
+}
+
+// bruteForceMAE() returns the median of "values" and their mean
+// absolute deviation from it.
+func bruteForceMAE(values []float64) (median, mae float64) {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	median = sorted[(n-1)/2]
+	for _,v := range sorted {
+		mae += math.Abs(v - median)
+	}
+	mae /= float64(n)
+	if n % 2 == 0 {
+		median = (sorted[n/2-1] + sorted[n/2])/2.0
+	}
+	return median, mae
+}
+
+func TestMAEAccumulator (t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	ma := NewMAEAccumulator()
+	values := make([]float64, 0)
+	for step:=0; step<2000; step++ {
+		if len(values) > 0 && rng.Intn(3) == 0 {
+			i := rng.Intn(len(values))
+			ma.Remove(values[i])
+			values = append(values[0:i], values[i+1:]...)
+		} else {
+			// Few distinct values, so that there are many ties.
+			v := float64(rng.Intn(20))
+			ma.Add(v)
+			values = append(values, v)
+		}
+		if len(values) == 0 {
+			continue
+		}
+		median,mae := bruteForceMAE(values)
+		if ma.Count() != len(values) || ma.Estimate() != median || !closeTo(ma.Metric(), mae) {
+			t.Fatalf ("step %d: count %d, median %g, MAE %g; expected %d, %g, %g", step, ma.Count(), ma.Estimate(), ma.Metric(), len(values), median, mae)
+		}
+	}
+
+	clone := ma.Clone().(*MAEAccumulator)
+	ma.Clear()
+	if ma.Count() != 0 || clone.Count() != len(values) {
+		t.Errorf ("Clear() affected the clone")
+	}
+	sort.Float64s(values)
+	for i,v := range clone.values() {
+		if v != values[i] {
+			t.Fatalf ("values() returned %v; expected %v", clone.values(), values)
+		}
+	}
+}
+
+func TestSplitCriteria (t *testing.T) {
+	data := []*Data {
+		&Data{output: 1.0, featureSelector: func (int32) float64 {return 1.0}},
+		&Data{output: 1.0, featureSelector: func (int32) float64 {return 2.0}},
+		&Data{output: 2.0, featureSelector: func (int32) float64 {return 3.0}},
+		&Data{output: 2.0, featureSelector: func (int32) float64 {return 4.0}},
+		&Data{output: 100.0, featureSelector: func (int32) float64 {return 5.0}}}
+
+	// Isolating the outlier leaves deviations of 0, 0, 1 and 1 from
+	// the median of the left partition.
+	split := continuousFeatureSplit(data, 0, unitWeightFactory(MAEAccumulatorFactory()))
+	if split.splitValue != 5.0 || !closeTo(split.compositeSplitMetric, 0.4) {
+		t.Errorf ("MAE split at %g with metric %g; expected 5 and 0.4", split.splitValue, split.compositeSplitMetric)
+	}
+
+	tree := NewTree(MAEAccumulatorFactory())
+	tree.SetMaxDepth(1)
+	tree.Train(data)
+	if e := tree.Classify(func (int32) float64 {return 4.0}).Estimate(); e != 1.5 {
+		t.Errorf ("MAE tree estimate is %g; expected the median 1.5", e)
+	}
+
+	tree = NewTree(PoissonAccumulatorFactory())
+	tree.SetMaxDepth(1)
+	tree.Train(data)
+	if e := tree.Classify(func (int32) float64 {return 5.0}).Estimate(); e != 100.0 {
+		t.Errorf ("Poisson tree estimate is %g; expected 100", e)
+	}
+}
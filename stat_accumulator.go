@@ -6,12 +6,19 @@ import (
 	"io"
 )
 
+// StatAccumulator accumulates the mean and variance of its inputs.
+// It keeps the count, the mean and the sum of squared deviations
+// from the mean, which are updated with Welford's method so that the
+// variance stays accurate when the inputs are large compared to
+// their spread.
+//
 // A new StatAccumulator may be declared without initialization.
 // The Go default initialization is correct.
 type StatAccumulator struct {
 	count int
-	sum float64
-	sumOfSquares float64
+	mean float64
+	// m2 is the sum of squared deviations from the mean.
+	m2 float64
 }
 
 func StatAccumulatorFactory() func() CVAccumulator {
@@ -23,56 +30,78 @@ func StatAccumulatorFactory() func() CVAccumulator {
 func (sa *StatAccumulator) Clone() ErrorAccumulator {
 	return &StatAccumulator{
 		count: sa.count,
-		sum: sa.sum,
-		sumOfSquares: sa.sumOfSquares}
+		mean: sa.mean,
+		m2: sa.m2}
 }
 
 func (sa *StatAccumulator) Add(x float64) {
 	sa.count += 1
-	sa.sum += x
-	sa.sumOfSquares += x*x
+	delta := x - sa.mean
+	sa.mean += delta/float64(sa.count)
+	sa.m2 += delta*(x - sa.mean)
 }
 
+// Remove() reverses Add(x).
 func (sa *StatAccumulator) Remove(x float64) {
 	if sa.count == 0 {
 		panic(errors.New("More calls to Remove() than to Add()"))
 	}
 	sa.count -= 1
-	sa.sum -= x
-	sa.sumOfSquares -= x*x
+	if sa.count == 0 {
+		// Avoid leaving rounding residue behind in an empty accumulator.
+		sa.Clear()
+		return
+	}
+	previousMean := sa.mean - (x - sa.mean)/float64(sa.count)
+	sa.m2 -= (x - previousMean)*(x - sa.mean)
+	sa.mean = previousMean
+	// Removing a value can only leave rounding error, never a
+	// negative sum of squares.
+	if sa.m2 < 0.0 {
+		sa.m2 = 0.0
+	}
+}
+
+// Merge() adds the inputs accumulated by "other", which must be a
+// *StatAccumulator, as if they had been added to "sa".
+func (sa *StatAccumulator) Merge(other ErrorAccumulator) {
+	o,ok := other.(*StatAccumulator)
+	if !ok {
+		panic(errors.New(fmt.Sprintf("Cannot merge %T into a StatAccumulator", other)))
+	}
+	if o.count == 0 {
+		return
+	}
+	n := sa.count + o.count
+	delta := o.mean - sa.mean
+	sa.mean += delta*float64(o.count)/float64(n)
+	sa.m2 += o.m2 + delta*delta*float64(sa.count)*float64(o.count)/float64(n)
+	sa.count = n
 }
 
 func (sa *StatAccumulator) Count() int {
 	return sa.count
 }
 
+// Metric() returns the variance.
 func (sa *StatAccumulator) Metric() float64 {
-	n := float64(sa.count)
-	result := 0.0
-	if sa.count > 0 {
-		result = (sa.sumOfSquares - sa.sum*sa.sum/n)/n
-	}
-	if result < 0.0 {
-		panic(errors.New("Error is negative"))
+	if sa.count == 0 {
+		return 0.0
 	}
-	return result
+	return sa.m2/float64(sa.count)
 }
 
+// Estimate() returns the mean.
 func (sa *StatAccumulator) Estimate() float64 {
-	result := 0.0
-	if sa.count > 0 {
-		result = sa.sum/float64(sa.count)
-	}
-	return result
+	return sa.mean
 }
 
 func (sa *StatAccumulator) Clear() {
 	sa.count = 0
-	sa.sum = 0.0
-	sa.sumOfSquares = 0.0
+	sa.mean = 0.0
+	sa.m2 = 0.0
 }
 
 func (sa *StatAccumulator) Dump(w io.Writer, indent int) {
-	fmt.Fprintf (w, "%*scount: %d, sum(x): %g, sum(x^2): %g\n", indent, "", sa.sum, sa.sumOfSquares)
+	fmt.Fprintf (w, "%*scount: %d, mean: %g, sum((x-mean)^2): %g\n", indent, "", sa.count, sa.mean, sa.m2)
 }
-
@@ -2,6 +2,8 @@ package ML
 
 import (
 	"fmt"
+	"math"
+	"math/rand"
 	"testing"
 )
 
@@ -37,3 +39,83 @@ func TestStatAccumulator (t *testing.T) {
 
 	checkMeanAndVariance(t, s, a, 18.0, 0.0)
 }
+
+func TestStatAccumulatorLargeOffset (t *testing.T) {
+	// The variance of values far from zero must not be lost to
+	// cancellation, nor become negative after removals.
+	offset := 1.0e9
+	values := []float64{4.0, 7.0, 13.0, 16.0}
+	var a StatAccumulator
+	var w WeightedStatAccumulator
+	for _,v := range values {
+		a.Add(offset + v)
+		w.Add(offset + v, 2.0)
+	}
+	if a.Estimate() != offset + 10.0 || a.Metric() != 22.5 {
+		t.Errorf ("expected mean %g and variance 22.5; got %v and %v", offset + 10.0, a.Estimate(), a.Metric())
+	}
+	if w.Estimate() != offset + 10.0 || w.Metric() != 22.5 {
+		t.Errorf ("weighted: expected mean %g and variance 22.5; got %v and %v", offset + 10.0, w.Estimate(), w.Metric())
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	present := make([]float64, 0)
+	var b StatAccumulator
+	for i:=0; i<2000; i++ {
+		if len(present) > 0 && rng.Intn(3) == 0 {
+			j := rng.Intn(len(present))
+			b.Remove(present[j])
+			present = append(present[:j], present[j+1:]...)
+		} else {
+			x := offset + rng.Float64()
+			b.Add(x)
+			present = append(present, x)
+		}
+		if b.Metric() < 0.0 {
+			t.Fatalf ("negative variance %v after %d operations", b.Metric(), i+1)
+		}
+	}
+	mean := 0.0
+	for _,x := range present {
+		mean += (x - offset)/float64(len(present))
+	}
+	variance := 0.0
+	for _,x := range present {
+		variance += (x - offset - mean)*(x - offset - mean)/float64(len(present))
+	}
+	if math.Abs(b.Metric() - variance) > 1.0e-6 {
+		t.Errorf ("variance after removals is %v; expected %v", b.Metric(), variance)
+	}
+}
+
+func TestStatAccumulatorMerge (t *testing.T) {
+	values := []float64{3.0, 6.0, 18.0, 1.0, 2.0}
+	var all, first, second StatAccumulator
+	var wAll, wFirst, wSecond WeightedStatAccumulator
+	for i,v := range values {
+		all.Add(v)
+		wAll.Add(v, float64(i + 1))
+		if i < 2 {
+			first.Add(v)
+			wFirst.Add(v, float64(i + 1))
+		} else {
+			second.Add(v)
+			wSecond.Add(v, float64(i + 1))
+		}
+	}
+	first.Merge(&second)
+	wFirst.Merge(&wSecond)
+	if first.Count() != all.Count() || !closeTo(first.Estimate(), all.Estimate()) || !closeTo(first.Metric(), all.Metric()) {
+		t.Errorf ("merged mean and variance are %v and %v; expected %v and %v", first.Estimate(), first.Metric(), all.Estimate(), all.Metric())
+	}
+	if wFirst.Count() != wAll.Count() || !closeTo(wFirst.WeightedCount(), wAll.WeightedCount()) ||
+			!closeTo(wFirst.Estimate(), wAll.Estimate()) || !closeTo(wFirst.Metric(), wAll.Metric()) {
+		t.Errorf ("weighted merged mean and variance are %v and %v; expected %v and %v", wFirst.Estimate(), wFirst.Metric(), wAll.Estimate(), wAll.Metric())
+	}
+
+	var empty StatAccumulator
+	empty.Merge(&all)
+	if !closeTo(empty.Metric(), all.Metric()) || empty.Count() != all.Count() {
+		t.Errorf ("merge into an empty accumulator gave variance %v; expected %v", empty.Metric(), all.Metric())
+	}
+}
@@ -0,0 +1,127 @@
+package ML
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// featureComponent structure is used for random subspace features
+// where the data is partitioned on a random linear combination of
+// elements of the feature vector.
+type featureComponent struct {
+	index int
+	weight float64 }
+
+// splitMix64 is a small, fast generator used to expand a seed into a
+// random projection each time a feature is selected, which is far
+// cheaper than seeding a rand.Source.
+type splitMix64 uint64
+
+func (s *splitMix64) next() uint64 {
+	*s += 0x9e3779b97f4a7c15
+	z := uint64(*s)
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+func (s *splitMix64) intn(n int) int {
+	return int(s.next() % uint64(n))
+}
+
+// float64() returns a value in [0,1).
+func (s *splitMix64) float64() float64 {
+	return float64(s.next() >> 11) / (1 << 53)
+}
+
+// maxSubspaceDimension() returns the largest number of components in
+// a random subspace over "featureVectorSize" features: about the
+// square root of the number of features, but at least two.
+func maxSubspaceDimension(featureVectorSize int) int {
+	result := int(math.Ceil(math.Sqrt(float64(featureVectorSize))))
+	if result < 2 {
+		result = 2
+	}
+	if result > featureVectorSize {
+		result = featureVectorSize
+	}
+	return result
+}
+
+// forEachComponent() calls "f" with the index and weight of each
+// component of the random subspace selected by "seed" over
+// "featureVectorSize" features.  The subspace has between one and
+// maxSubspaceDimension() components with weights uniform in [-1,1).
+// An index may appear more than once.  The same seed always selects
+// the same subspace.
+func forEachComponent(seed int32, featureVectorSize int, f func(index int, weight float64)) {
+	if featureVectorSize == 0 {
+		return
+	}
+	s := splitMix64(uint32(seed))
+	componentDim := 1 + s.intn(maxSubspaceDimension(featureVectorSize))
+	for i:=0; i<componentDim; i++ {
+		index := s.intn(featureVectorSize)
+		f(index, s.float64()*2.0 - 1.0)
+	}
+}
+
+// randomSubspace() returns the components of the random subspace
+// selected by "seed" (see forEachComponent()).
+func randomSubspace(seed int32, featureVectorSize int) []featureComponent {
+	result := make([]featureComponent, 0)
+	forEachComponent(seed, featureVectorSize, func(index int, weight float64) {
+		result = append(result, featureComponent{index, weight})
+	})
+	return result
+}
+
+// RandomSubspaceSelector() returns a feature selector for which each
+// seed selects the projection of "features" on a sparse random
+// linear combination of its elements.  Trees grown on records with
+// these selectors make oblique splits.  A projection involving a
+// missing (NaN) feature is missing.
+func RandomSubspaceSelector(features []float64) func(int32) float64 {
+	return func(seed int32) float64 {
+		result := 0.0
+		forEachComponent(seed, len(features), func(index int, weight float64) {
+			result += weight*features[index]
+		})
+		return result
+	}
+}
+
+// UseRandomSubspaces() gives each record in "data" a
+// RandomSubspaceSelector over its continuous features.
+func UseRandomSubspaces(data []*Data) {
+	for _,d := range data {
+		d.SetFeatureSelector(RandomSubspaceSelector(d.continuousFeatures))
+	}
+}
+
+// RandomSubspaceDescriber() returns a FeatureDescriber for
+// RandomSubspaceSelector() over "featureVectorSize" features, which
+// are named after "names" if it is not nil and x0, x1, ... otherwise.
+func RandomSubspaceDescriber(featureVectorSize int, names []string) FeatureDescriber {
+	return FeatureDescriberFunc(func(seed int32) string {
+		if seed < -1 {
+			return describeFeature(nil, seed)
+		}
+		terms := make([]string, 0)
+		for _,c := range randomSubspace(seed, featureVectorSize) {
+			name := fmt.Sprintf("x%d", c.index)
+			if c.index < len(names) {
+				name = names[c.index]
+			}
+			if len(terms) == 0 {
+				terms = append(terms, fmt.Sprintf("%.3g*%s", c.weight, name))
+			} else if c.weight < 0 {
+				terms = append(terms, fmt.Sprintf("- %.3g*%s", -c.weight, name))
+			} else {
+				terms = append(terms, fmt.Sprintf("+ %.3g*%s", c.weight, name))
+			}
+		}
+		return strings.Join(terms, " ")
+	})
+}
@@ -0,0 +1,63 @@
+package ML
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestRandomSubspace (t *testing.T) {
+	features := []float64{1.0, 2.0, 3.0, 4.0, 5.0, 6.0, 7.0, 8.0, 9.0, 10.0}
+	selector := RandomSubspaceSelector(features)
+	for seed:=int32(0); seed<100; seed++ {
+		components := randomSubspace(seed, len(features))
+		if len(components) < 1 || len(components) > maxSubspaceDimension(len(features)) {
+			t.Errorf ("seed %d has %d components", seed, len(components))
+		}
+		expected := 0.0
+		for _,c := range components {
+			if c.index < 0 || c.index >= len(features) || c.weight < -1.0 || c.weight >= 1.0 {
+				t.Errorf ("seed %d has bad component %v", seed, c)
+			}
+			expected += c.weight*features[c.index]
+		}
+		if v := selector(seed); v != expected || v != selector(seed) {
+			t.Errorf ("seed %d selected %g; expected %g", seed, v, expected)
+		}
+	}
+
+	if !math.IsNaN(RandomSubspaceSelector([]float64{math.NaN()})(1)) {
+		t.Errorf ("expected a projection of a missing feature to be missing")
+	}
+
+	d := RandomSubspaceDescriber(2, []string{"width", "height"}).DescribeFeature(3)
+	if !strings.Contains(d, "*width") && !strings.Contains(d, "*height") {
+		t.Errorf ("unexpected description \"%s\"", d)
+	}
+}
+
+func TestObliqueTree (t *testing.T) {
+	// The classes are separated by a diagonal line.
+	rng := rand.New(rand.NewSource(1))
+	data := make([]*Data, 0)
+	for i:=0; i<200; i++ {
+		features := []float64{rng.Float64(), rng.Float64()}
+		output := 0.0
+		if features[0] + features[1] > 1.0 {
+			output = 1.0
+		}
+		data = append(data, &Data{continuousFeatures: features, output: output, outputCategories: 2, weight: 1.0})
+	}
+	UseRandomSubspaces(data)
+
+	tree := NewTree(EntropyAccumulatorFactory(2))
+	tree.SetFeaturesToTry(20)
+	tree.SetRand(rand.New(rand.NewSource(2)))
+	tree.Train(data)
+	for _,d := range data {
+		if e := tree.Classify(RandomSubspaceSelector(d.continuousFeatures)).Estimate(); e != d.output {
+			t.Errorf ("%v classified as %g; expected %g", d.continuousFeatures, e, d.output)
+		}
+	}
+}
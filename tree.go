@@ -8,14 +8,6 @@ import (
 	"math/rand"
 )
 
-// featureComponent structure is used for random subspace features
-// where the data is partitioned on a random linear combination of
-// elements of the feature vector.  
-type featureComponent struct {
-	index int
-	weight float64 }
-
-
 const (
 	CATEGORICAL FeatureType =  iota
 	CONTINUOUS
@@ -23,121 +15,204 @@ const (
 
 type SplitInfo struct {
 	compositeSplitMetric float64
+	featureType FeatureType
 	splitValue float64
-	left, right CVAccumulator
+	// For a CATEGORICAL split, leftCategories[c] is true if records
+	// with category c belong to the left partition.
+	leftCategories []bool
+	// missingLeft is true if records with a missing feature value
+	// belong to the left partition.
+	missingLeft bool
+	left, right WeightedCVAccumulator
 }
 
 func (si *SplitInfo) String() string {
-	s := fmt.Sprintf ("{ compositeSplitMetric: %g splitValue %g\n", si.compositeSplitMetric, si.splitValue)
+	s := fmt.Sprintf ("{ compositeSplitMetric: %g splitValue %g missingLeft %v\n", si.compositeSplitMetric, si.splitValue, si.missingLeft)
 	s = s + fmt.Sprintf ("   left: [%v]\n   right: [%v]\n}", si.left, si.right)
 	return s
 }
 
+// compositeMetric() returns the weighted average of the left and
+// right partition metrics.
+func compositeMetric(left, right WeightedCVAccumulator) float64 {
+	leftWeight := left.WeightedCount()
+	rightWeight := right.WeightedCount()
+	return (leftWeight*left.Metric() + rightWeight*right.Metric())/(leftWeight+rightWeight)
+}
+
 // Split the data with a continuously valued output variable along the
 // continuously valued feature axis.  Return the feature value for the
 // split, the left and right partition metric after the split, and
 // the size of the left split.  The returned size will be zero if the
-// error cannot be reduced.
-func continuousFeatureSplit (data []*Data, seed int32, accumulatorFactory func() CVAccumulator) (splitInfo SplitInfo) {
+// error cannot be reduced.  Records contribute to the partition
+// metrics in proportion to their weight.
+//
+// Records whose feature value is missing (NaN) are sent to whichever
+// side of each candidate split gives the lower metric, and the
+// chosen side is returned in missingLeft.  Sending all missing
+// values one way and all others the other way is also a candidate.
+func continuousFeatureSplit (data []*Data, seed int32, accumulatorFactory func() WeightedCVAccumulator) (splitInfo SplitInfo) {
 	left := accumulatorFactory()
 	right := accumulatorFactory()
+	// leftWithMissing and rightWithMissing are the left and right
+	// partitions with the missing values added.
+	leftWithMissing := accumulatorFactory()
+	rightWithMissing := accumulatorFactory()
 
 	left.Clear()
 	right.Clear()
+	leftWithMissing.Clear()
+	rightWithMissing.Clear()
 	
-	s := sortableData{data, seed}
-	sort.Sort(s)
+	s := newSortableData(data, seed)
+	present := s.partitionMissing()
+	sort.Sort(s.slice(0, present))
 
-	for _,row := range data {
-		right.Add(row.output)
+	for i,row := range data {
+		if i < present {
+			right.Add(row.output, row.weight)
+		} else {
+			leftWithMissing.Add(row.output, row.weight)
+		}
+		rightWithMissing.Add(row.output, row.weight)
 	}
-
-	rightMetric := right.Metric()
+	hasMissing := present < len(data)
 
 	splitInfo = SplitInfo {
-		// Initialize splitValue with the *output* estimate
+		featureType: CONTINUOUS,
 		splitValue: 0.0,
-		compositeSplitMetric: rightMetric,
-		left: left.Clone().(CVAccumulator),
-		right: right.Clone().(CVAccumulator)}
-	
-	previousSplitCandidate := - math.MaxFloat64
+		compositeSplitMetric: rightWithMissing.Metric()}
+
+	// bestSize is the number of records with a value in the best
+	// left partition found so far.
+	bestSize := 0
+	consider := func(splitValue float64, size int, l, r WeightedCVAccumulator, missingLeft bool) {
+		error := compositeMetric(l, r)
+		if error < splitInfo.compositeSplitMetric {
+			// Values greater than or equal to the split value
+			// belong to the right partition.
+			splitInfo.splitValue = splitValue
+			splitInfo.compositeSplitMetric = error
+			splitInfo.missingLeft = missingLeft
+			bestSize = size
+		}
+	}
 
-	for i,row := range data {
-		fv := row.featureSelector(seed)
-		if (i != 0 && fv != previousSplitCandidate) {
-			if (fv <= previousSplitCandidate) {
-				fmt.Printf ("Sanity check: fv=%g <= previousSplitCandidate=%g\n", fv, previousSplitCandidate)
-			}
-			leftMetric := left.Metric()
-			rightMetric := right.Metric()
-			leftCount := left.Count()
-			rightCount := right.Count()
-			error := (float64(leftCount)*leftMetric + float64(rightCount)*rightMetric)/float64(leftCount+rightCount)
-			if error < splitInfo.compositeSplitMetric && leftCount > 0 && rightCount > 0 {
-				sv := 0.5*(previousSplitCandidate+fv)
-				// When previousSplitCandidate and fv are extremely close, split value can exactly match
-				// previousSplitCandidate.  In that case, use the upper value (fv)
-				if sv == previousSplitCandidate {
-					sv = fv
-				}
-				splitInfo.left = left.Clone().(CVAccumulator)
-				splitInfo.right = right.Clone().(CVAccumulator)
-				splitInfo.splitValue = sv
-				splitInfo.compositeSplitMetric = error
+	for i,row := range data[0:present] {
+		fv := s.values[i]
+		if i != 0 && fv != s.values[i-1] {
+			consider(fv, i, left, rightWithMissing, false)
+			if hasMissing {
+				consider(fv, i, leftWithMissing, right, true)
 			}
 		}
-		left.Add(row.output)
-		right.Remove(row.output)
-
-		previousSplitCandidate = fv
+		left.Add(row.output, row.weight)
+		right.Remove(row.output, row.weight)
+		leftWithMissing.Add(row.output, row.weight)
+		rightWithMissing.Remove(row.output, row.weight)
 	}
 
-	leftCount := 0
-	rightCount := 0
-	maxBeforeSplit := -math.MaxFloat64
-	minAfterSplit := math.MaxFloat64
-	if left.Count() != 0 && right.Count() != 0 {
-		for _,row := range data {
-			fv := row.featureSelector(seed)
-			if (fv < splitInfo.splitValue) {
-				leftCount += 1
-				if fv > maxBeforeSplit {
-					maxBeforeSplit = fv
-				}
-			} else {
-				rightCount += 1
-				if fv < minAfterSplit {
-					minAfterSplit = fv
-				}
-			}
+	// Split the records with a value from the missing ones.  The
+	// split value must exceed the largest value.
+	if hasMissing && present > 0 {
+		if above := math.Nextafter(s.values[present-1], math.Inf(1)); !math.IsInf(above, 1) {
+			consider(above, present, left, rightWithMissing, false)
 		}
-		if leftCount != left.Count() || rightCount != right.Count() {
-			fmt.Printf ("leftCount=%d,leftSplitSize=%d,rightCount=%d,rightSplitSize=%d\nmaxbeforeSplit=%g,splitValue=%g,minAfterSplit=%g\n",
-				leftCount, left.Count(),rightCount,right.Count(),maxBeforeSplit,splitInfo.splitValue,minAfterSplit)
+	}
+
+	// Rebuild the partition statistics for the best split rather
+	// than cloning them every time the best split improves.
+	left.Clear()
+	right.Clear()
+	for i,row := range data {
+		if i < bestSize || (i >= present && splitInfo.missingLeft) {
+			left.Add(row.output, row.weight)
+		} else {
+			right.Add(row.output, row.weight)
 		}
 	}
+	if !hasMissing {
+		splitInfo.missingLeft = heavierIsLeft(left, right)
+	}
+	splitInfo.left = left
+	splitInfo.right = right
 	return
 }
 
+// heavierIsLeft() returns true if the left partition has more weight
+// than the right.  Splits whose training data has no missing values
+// send missing values to the heavier partition.
+func heavierIsLeft(left, right WeightedCVAccumulator) bool {
+	return left.WeightedCount() > right.WeightedCount()
+}
+
 type Tree struct {
 	root *treeNode
 	maxDepth int
 	minLeafSize int
 	featuresToTry int
-	randomSubspace []featureComponent
-	accumulatorFactory func() CVAccumulator
+	accumulatorFactory func() WeightedCVAccumulator
 	errorAccumulator ErrorAccumulator
+
+	// rng is the source of all random choices made while growing
+	// the tree.
+	rng *rand.Rand
+
+	// weighted is false if record weights are ignored.
+	weighted bool
+
+	// featureDescriber describes split features in Dump().
+	featureDescriber FeatureDescriber
+
+	// maxBins is the number of bins of binned splits, or 0 for
+	// exact splits (see SetMaxBins()).  binEdges holds the bin
+	// edges of the columns of the training set.
+	maxBins int
+	binEdges map[int32][]float64
+
+	// extraTrees is true if continuous features are split at a
+	// random cut point (see SetExtraTrees()).
+	extraTrees bool
+
+	// Further stopping rules (see SetMinSplitSize() and the
+	// following).  totalWeight is the weight of the training set.
+	minSplitSize int
+	minImpurityDecrease float64
+	maxLeaves int
+	minWeightFraction float64
+	totalWeight float64
 }
 
+// NewTree() returns a tree whose nodes accumulate statistics with
+// accumulators from "accumulatorFactory".  Record weights are
+// ignored.
 func NewTree (accumulatorFactory func() CVAccumulator) *Tree {
+	tree := NewWeightedTree(unitWeightFactory(accumulatorFactory))
+	tree.weighted = false
+	return tree
+}
+
+// NewWeightedTree() returns a tree that uses record weights (see
+// Data.SetWeight()) when choosing splits and computing leaf
+// statistics.
+func NewWeightedTree (accumulatorFactory func() WeightedCVAccumulator) *Tree {
 	return &Tree{
 		root: nil,
 		maxDepth: int(math.MaxInt32),
 		minLeafSize: 1,
 		featuresToTry: 1,
 		accumulatorFactory: accumulatorFactory,
-		errorAccumulator: &errorAccumulator{}}
+		errorAccumulator: &errorAccumulator{},
+		rng: rand.New(rand.NewSource(rand.Int63())),
+		weighted: true}
+}
+
+// recordWeight() returns the weight of "d" as seen by the tree.
+func (tree *Tree) recordWeight(d *Data) float64 {
+	if tree.weighted {
+		return d.weight
+	}
+	return 1.0
 }
 
 func (tree *Tree) SetMaxDepth(depth int) {
@@ -148,25 +223,80 @@ func (tree *Tree) SetMinLeafSize(size int) {
 	tree.minLeafSize = size
 }
 
+// SetMinSplitSize() sets the smallest number of records a node must
+// have to be split.  The default, 0, splits any node.
+func (tree *Tree) SetMinSplitSize(size int) {
+	tree.minSplitSize = size
+}
+
+// SetMinImpurityDecrease() sets the smallest decrease in metric for
+// which a node is split, where the decrease is weighted by the
+// fraction of the training weight that reaches the node.  The
+// default, 0, splits on any decrease.
+func (tree *Tree) SetMinImpurityDecrease(decrease float64) {
+	tree.minImpurityDecrease = decrease
+}
+
+// SetMaxLeaves() limits the tree to "n" leaves.  The tree is then
+// grown best first: the leaf whose split most decreases the weighted
+// metric is split next.  The default, 0, grows the tree depth first
+// without a limit.
+func (tree *Tree) SetMaxLeaves(n int) {
+	tree.maxLeaves = n
+}
+
+// SetMinWeightFraction() sets the smallest fraction of the training
+// weight that each leaf must hold.  The default is 0.
+func (tree *Tree) SetMinWeightFraction(fraction float64) {
+	tree.minWeightFraction = fraction
+}
+
+// SetFeaturesToTry() sets the number of candidate features tried at
+// each node.  For records that select columns (see
+// UseColumnSelectors()) they are distinct columns; n equal to the
+// number of columns tries every column.
 func (tree *Tree) SetFeaturesToTry(n int) {
 	tree.featuresToTry = n
 }
 
+// SetFeatureDescriber() sets the FeatureDescriber used by Dump() to
+// describe the feature of each split.  It is typically the feature
+// selector of any training record, such as a *HierarchicalFeatures.
+func (tree *Tree) SetFeatureDescriber(describer FeatureDescriber) {
+	tree.featureDescriber = describer
+}
+
+// SetRand() sets the random source used by Train().  A tree grown
+// from a source with a given seed is always the same.  Trees that
+// are trained concurrently must not share a source.
+func (tree *Tree) SetRand(rng *rand.Rand) {
+	tree.rng = rng
+}
+
+// Train() grows the tree on "trainingSet", replacing any previous
+// tree.  It panics if the records of a weighted tree have no total
+// weight, as records whose weight was never set (see
+// Data.SetWeight()) have weight 0.
 func (tree *Tree) Train(trainingSet[] *Data) {
+	if tree.weighted {
+		requirePositiveWeight(trainingSet, "Tree.Train()")
+	}
 	statistics := tree.accumulatorFactory()
 	for _,d := range trainingSet {
-		statistics.Add(d.output)
+		statistics.Add(d.output, d.weight)
 	}
 	tree.root = NewTreeNode(statistics)
-	tree.root.grow(trainingSet,
-		tree.maxDepth,
-		tree.minLeafSize,
-		tree.featuresToTry,
-		tree.accumulatorFactory)
+	tree.totalWeight = statistics.WeightedCount()
+	tree.computeBinEdges(trainingSet)
+	if tree.maxLeaves > 0 {
+		tree.root.growBestFirst(trainingSet, tree.maxDepth, tree)
+	} else {
+		tree.root.grow(trainingSet, tree.maxDepth, tree)
+	}
 }
 
 func (tree *Tree) Classify(featureSelector func(int32) float64) CVAccumulator {
-	return tree.root.classify(featureSelector)
+	return asCVAccumulator(tree.root.classify(featureSelector))
 }
 
 func (tree *Tree) Add(error float64) {
@@ -177,8 +307,10 @@ func (tree *Tree) Estimate() float64 {
 	return tree.errorAccumulator.Estimate()
 }
 
+// Dump() writes the tree to "w", describing split features with the
+// tree's FeatureDescriber if it has one.
 func (tree *Tree) Dump(w io.Writer) {
-	tree.root.dump(w, 0, 0)
+	tree.root.dump(w, 0, 0, tree.featureDescriber)
 }
 
 func (tree *Tree) Size() int {
@@ -198,7 +330,7 @@ type treeNode struct {
 
 	// statistics contains the accumulated statistics for the
 	// training data used to generate this node.
-	statistics CVAccumulator
+	statistics WeightedCVAccumulator
 
 	// In a leaf node, "seed" is -1, and the "left" and "right"
 	// pointers are nil.  In a non-leaf node, "seed" is the seed
@@ -207,13 +339,24 @@ type treeNode struct {
 	left *treeNode
 	right *treeNode
 	
-	// In a non-leaf node, splitValue is the splitting value.
-	// Values greater than or equal to the splitting value belong
-	// to the right subtree.  Others belong to the left subtree.
+	// In a CONTINUOUS non-leaf node, splitValue is the splitting
+	// value.  Values greater than or equal to the splitting value
+	// belong to the right subtree.  Others belong to the left
+	// subtree.
 	splitValue float64
+
+	// In a CATEGORICAL non-leaf node, "seed" is categoricalSeed() of
+	// the splitting column and leftCategories[c] is true if category
+	// c belongs to the left subtree.  Other categories, including
+	// ones not seen during training, belong to the right subtree.
+	leftCategories []bool
+
+	// In a non-leaf node, missingLeft is true if records whose
+	// splitting feature is missing (NaN) belong to the left subtree.
+	missingLeft bool
 }
 
-func NewTreeNode (statistics CVAccumulator) *treeNode {
+func NewTreeNode (statistics WeightedCVAccumulator) *treeNode {
 	return &treeNode{
 		featureType: CONTINUOUS,
 		seed: -1,
@@ -222,17 +365,30 @@ func NewTreeNode (statistics CVAccumulator) *treeNode {
 		splitValue: math.MaxFloat64}
 }
 
-// splitData() splits "data" into a "left" and "right" portions based on "splitValue" and "splitFeatureIndex".
-func splitData (data []*Data, splitValue float64, seed int32, left[]*Data, right[]*Data) {
+// goesLeft() returns true if a record whose value of the splitting
+// feature is "value" belongs to the left subtree.
+func (tree *treeNode) goesLeft(value float64) bool {
+	if math.IsNaN(value) {
+		return tree.missingLeft
+	}
+	if tree.featureType == CATEGORICAL {
+		category := int(value)
+		return category >= 0 && category < len(tree.leftCategories) && tree.leftCategories[category]
+	}
+	return value < tree.splitValue
+}
+
+// splitData() splits "data" into a "left" and "right" portions according to the split at "tree".
+func splitData (data []*Data, tree *treeNode, left[]*Data, right[]*Data) {
 	leftCount := 0
 	leftSize := len(left)
 	rightCount := 0
 	rightSize := len(right)
 	for _,dp := range data {
-		if dp.featureSelector(seed) < splitValue {
+		if tree.goesLeft(dp.feature(tree.seed)) {
 			if (leftCount == leftSize) {
 				fmt.Printf("leftSize=%d; rightSize=%d; leftCount=%d; rightCount=%d\n", leftSize, rightSize, leftCount, rightCount)
-				fmt.Printf("splitValue=%g, feature=%g\n", splitValue, dp.featureSelector(seed))
+				fmt.Printf("splitValue=%g, feature=%g\n", tree.splitValue, dp.feature(tree.seed))
 				panic ("Split sizes are not as expected in splitData()")
 			}
 			left[leftCount] = dp
@@ -240,108 +396,189 @@ func splitData (data []*Data, splitValue float64, seed int32, left[]*Data, right
 		} else {
 			if (rightCount == rightSize) {
 				fmt.Printf("leftSize=%d; rightSize=%d; leftCount=%d; rightCount=%d\n", leftSize, rightSize, leftCount, rightCount)
-				fmt.Printf("splitValue=%g, feature=%g\n", splitValue, dp.featureSelector(seed))
+				fmt.Printf("splitValue=%g, feature=%g\n", tree.splitValue, dp.feature(tree.seed))
 				panic ("Split sizes are not as expected in splitData()")
 			}
 			right[rightCount] = dp
 			rightCount += 1
 		}
 	}
-	if leftSize != leftCount  || rightSize != rightCount {
+}
+
+// missingLabel() returns the dump annotation of the left ("left" is
+// true) or right branch for missing values.
+func (tree *treeNode) missingLabel(left bool) string {
+	if tree.missingLeft == left {
+		return " or missing"
 	}
+	return ""
 }
 
 // Dump() produces a visual representation of the tree on the io.Writer.  The parameter depth
-// is the depth of this node in the tree.
-func (tree *treeNode) dump(w io.Writer, index, depth int) {
+// is the depth of this node in the tree.  Split features are
+// described by "describer", which may be nil.
+func (tree *treeNode) dump(w io.Writer, index, depth int, describer FeatureDescriber) {
 	indent := 4*depth + 1
 	if tree.seed == -1 {
 		fmt.Fprintf (w, "%*c %2d - Leaf node - output: %g; metric: %g\n", indent, ' ', index, tree.statistics.Estimate(), tree.statistics.Metric())
-	} else {
-		fmt.Fprintf (w, "%*c %2d - Split on feature ??? at value %g; metric: %g\n", indent, ' ', index, tree.splitValue, tree.statistics.Metric())
+		return
+	}
 
-		fmt.Fprintf (w, "%*c %2d - Left branch (feature ??? < %g):\n", indent, ' ', index, tree.splitValue)
-		tree.left.dump(w, index+1, depth+1)
+	feature := describeFeature(describer, tree.seed)
+	if tree.featureType == CATEGORICAL {
+		left,right := tree.categorySets()
+		fmt.Fprintf (w, "%*c %2d - Split on %s; metric: %g\n", indent, ' ', index, feature, tree.statistics.Metric())
 
-		fmt.Fprintf (w, "%*c %2d - Right branch (feature ??? >= %g):\n", indent, ' ', index, tree.splitValue)
-		tree.right.dump(w, index+2, depth+1)
-	}
-}
+		fmt.Fprintf (w, "%*c %2d - Left branch (%s in %v%s):\n", indent, ' ', index, feature, left, tree.missingLabel(true))
+		tree.left.dump(w, index+1, depth+1, describer)
 
-func randomSubspace(featureVectorSize int) []featureComponent {
-	componentDim := featureVectorSize
-	
-	for i:=0; i<200; i++ {
-		r := int(rand.Int31n(int32(featureVectorSize))) + 1
-		if r < componentDim {
-			componentDim = r
-		}
-	}
+		fmt.Fprintf (w, "%*c %2d - Right branch (%s in %v or unseen%s):\n", indent, ' ', index, feature, right, tree.missingLabel(false))
+		tree.right.dump(w, index+2, depth+1, describer)
+	} else {
+		fmt.Fprintf (w, "%*c %2d - Split on %s at value %g; metric: %g\n", indent, ' ', index, feature, tree.splitValue, tree.statistics.Metric())
+
+		fmt.Fprintf (w, "%*c %2d - Left branch (%s < %g%s):\n", indent, ' ', index, feature, tree.splitValue, tree.missingLabel(true))
+		tree.left.dump(w, index+1, depth+1, describer)
 
-	result := make([]featureComponent,componentDim)
-	for i:=0; i<componentDim; i++ {
-		result[i].index = int(rand.Int31n(int32(featureVectorSize)))
-		result[i].weight = rand.Float64()*2.0 - 1.0;
+		fmt.Fprintf (w, "%*c %2d - Right branch (%s >= %g%s):\n", indent, ' ', index, feature, tree.splitValue, tree.missingLabel(false))
+		tree.right.dump(w, index+2, depth+1, describer)
 	}
-	return result
 }
 
 // grow() grows the tree based on the test set "data."  "featureSelector" is a function
 // of a feature record returning the abstract feature value.  continuousFeatureSplit
-// is the splitting function (e.g.,  MSE Error or entropy).
-func (tree *treeNode) grow(data []*Data, maxDepth, minLeafSize, featuresToTry int, accumulatorFactory func() CVAccumulator) {
-	if (len(data) == 0) {
-		return
+// is the splitting function (e.g.,  MSE Error or entropy).  The
+// remaining growth parameters and the random source are taken from
+// "config".
+func (tree *treeNode) grow(data []*Data, maxDepth int, config *Tree) {
+	splitInfo, seed := tree.bestSplit(data, maxDepth, config)
+	if seed != -1 {
+		leftData, rightData := tree.split(data, seed, splitInfo)
+		tree.left.grow(leftData, maxDepth-1, config)
+		tree.right.grow(rightData, maxDepth-1, config)
 	}
+}
 
-	if maxDepth == 0 {
+// bestSplit() returns the best split of "data" at "tree" that the
+// stopping rules of "config" allow, and the seed of its feature, or
+// -1 if the node should stay a leaf.  "maxDepth" is the remaining
+// depth.
+func (tree *treeNode) bestSplit(data []*Data, maxDepth int, config *Tree) (bestSplitInfo SplitInfo, bestSeed int32) {
+	bestSeed = -1
+	if len(data) == 0 || maxDepth == 0 || len(data) < config.minSplitSize {
 		return
 	}
 
-	var bestSplitInfo SplitInfo
 	bestMetric := tree.statistics.Metric()
+	minLeafWeight := config.minWeightFraction*config.totalWeight
+
+	// Each candidate is either a random continuous feature or one
+	// of the categorical columns, chosen in proportion to the
+	// number of features of each kind.
+	continuousCount := 0
+	if data[0].featureSelector != nil {
+		continuousCount = len(data[0].continuousFeatures)
+		if continuousCount == 0 {
+			continuousCount = 1
+		}
+	}
+	categoricalCount := len(data[0].categoricalFeatures)
+
+	// Records that select columns (see UseColumnSelectors()) try
+	// distinct columns, continuous or categorical.  Seed c selects
+	// continuous column c.
+	var columns []int
+	tries := config.featuresToTry
+	if data[0].selectsColumns {
+		columns = draw(make([]int, 0, tries), allIndices(continuousCount+categoricalCount), tries, false, config.rng)
+		tries = len(columns)
+	}
 
-	for i:= 0; i<featuresToTry; i++ {
-//		candidateSeed := rand.Int31n(int32(len(data[0].continuousFeatures)))
-		candidateSeed := rand.Int31()
-		candidateSplitInfo := continuousFeatureSplit(data, candidateSeed, accumulatorFactory)
+	for i:= 0; i<tries; i++ {
+		var candidateSeed int32
+		var candidateSplitInfo SplitInfo
+		if columns != nil {
+			if columns[i] < continuousCount {
+				candidateSeed = int32(columns[i])
+				candidateSplitInfo = config.continuousSplit(data, candidateSeed)
+			} else {
+				column := columns[i] - continuousCount
+				candidateSeed = categoricalSeed(column)
+				candidateSplitInfo = categoricalFeatureSplit(data, column, config)
+			}
+		} else if categoricalCount > 0 && config.rng.Intn(continuousCount+categoricalCount) < categoricalCount {
+			column := config.rng.Intn(categoricalCount)
+			candidateSeed = categoricalSeed(column)
+			candidateSplitInfo = categoricalFeatureSplit(data, column, config)
+		} else {
+			candidateSeed = config.rng.Int31()
+			candidateSplitInfo = config.continuousSplit(data, candidateSeed)
+		}
 
-		if candidateSplitInfo.left.Count() >= minLeafSize && 
-			candidateSplitInfo.right.Count() >= minLeafSize &&
+		if candidateSplitInfo.left.Count() >= config.minLeafSize &&
+			candidateSplitInfo.right.Count() >= config.minLeafSize &&
+			candidateSplitInfo.left.WeightedCount() >= minLeafWeight &&
+			candidateSplitInfo.right.WeightedCount() >= minLeafWeight &&
 			candidateSplitInfo.compositeSplitMetric < bestMetric {
 			bestSplitInfo = candidateSplitInfo
-			tree.seed = candidateSeed
+			bestSeed = candidateSeed
 			bestMetric = candidateSplitInfo.compositeSplitMetric
 		}
 	}
-	
-	if tree.seed != -1 {
-		tree.splitValue = bestSplitInfo.splitValue
-		
-		leftData := make([]*Data, bestSplitInfo.left.Count())
-		rightData := make([]*Data, bestSplitInfo.right.Count())
-		
-		splitData(data, tree.splitValue, tree.seed, leftData, rightData)
-		
-		tree.left = NewTreeNode(bestSplitInfo.left)
-		tree.right = NewTreeNode(bestSplitInfo.right)
-
-		tree.left.grow(leftData, maxDepth-1, minLeafSize, featuresToTry, accumulatorFactory)
-		tree.right.grow(rightData, maxDepth-1, minLeafSize, featuresToTry, accumulatorFactory)
+
+	if bestSeed != -1 && config.impurityDecrease(tree, bestSplitInfo) < config.minImpurityDecrease {
+		bestSeed = -1
 	}
+	return
+}
+
+// impurityDecrease() returns the decrease in metric from splitting
+// "tree" by "splitInfo", weighted by the fraction of the training
+// weight that reaches "tree".
+func (config *Tree) impurityDecrease(tree *treeNode, splitInfo SplitInfo) float64 {
+	fraction := 1.0
+	if config.totalWeight > 0.0 {
+		fraction = tree.statistics.WeightedCount()/config.totalWeight
+	}
+	return fraction*(tree.statistics.Metric() - splitInfo.compositeSplitMetric)
+}
+
+// split() makes "tree" a split by "splitInfo" on the feature
+// selected by "seed", with leaf children, and returns the records of
+// "data" that belong to each child.
+func (tree *treeNode) split(data []*Data, seed int32, splitInfo SplitInfo) (leftData, rightData []*Data) {
+	tree.seed = seed
+	tree.featureType = splitInfo.featureType
+	tree.splitValue = splitInfo.splitValue
+	tree.leftCategories = splitInfo.leftCategories
+	tree.missingLeft = splitInfo.missingLeft
+
+	leftData = make([]*Data, splitInfo.left.Count())
+	rightData = make([]*Data, splitInfo.right.Count())
+
+	splitData(data, tree, leftData, rightData)
+
+	tree.left = NewTreeNode(splitInfo.left)
+	tree.right = NewTreeNode(splitInfo.right)
+	return
 }
 
 // Classify (or predict) the passed feature vector.
-func (tree *treeNode) classify(featureSelector func(int32) float64) CVAccumulator {
+func (tree *treeNode) classify(featureSelector func(int32) float64) WeightedCVAccumulator {
+	return tree.leaf(featureSelector).statistics
+}
+
+// leaf() returns the leaf reached by the passed feature vector.
+func (tree *treeNode) leaf(featureSelector func(int32) float64) *treeNode {
 	// Leaf node?
 	if tree.seed == -1 {
-		return tree.statistics
+		return tree
 	} else {
 		switch  {
-		case featureSelector(tree.seed) < tree.splitValue:
-			return tree.left.classify(featureSelector)
+		case tree.goesLeft(featureSelector(tree.seed)):
+			return tree.left.leaf(featureSelector)
 		default:
-			return tree.right.classify(featureSelector)
+			return tree.right.leaf(featureSelector)
 		}
 	}
 }
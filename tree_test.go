@@ -1,13 +1,16 @@
 package ML
 
 import (
+	"bytes"
 	"math"
+	"math/rand"
 	"testing"
 	"os"
+	"strings"
 )
 
 func testDataMSE (t *testing.T, msg string, data []*Data, seed int32, output int, expectedSplit, expectedLeftError, expectedRightError float64, size int) {
-	splitInfo := continuousFeatureSplit(data, seed, StatAccumulatorFactory())
+	splitInfo := continuousFeatureSplit(data, seed, unitWeightFactory(StatAccumulatorFactory()))
 	if (splitInfo.splitValue != expectedSplit) {
 		t.Errorf ("%s: expected split: %v; got: %v", msg, expectedSplit, splitInfo.splitValue)
 	}
@@ -50,7 +53,7 @@ func TestContinuousFeatureMSESplit (t *testing.T) {
 func testDataEntropy (t *testing.T, msg string, data []*Data, seed int32, outputValueCount int, expectedSplit, expectedLeftEntropy, expectedRightEntropy float64, size int) {
 //	f := continuousFeatureEntropySplitter (outputValueCount)
 //	splitInfo := f(data, seed)
-	splitInfo := continuousFeatureSplit (data, seed, EntropyAccumulatorFactory(outputValueCount))
+	splitInfo := continuousFeatureSplit (data, seed, unitWeightFactory(EntropyAccumulatorFactory(outputValueCount)))
 	if (splitInfo.splitValue != expectedSplit) {
 		t.Errorf ("%s: expected split: %v; got: %v", msg, expectedSplit, splitInfo.splitValue)
 	}
@@ -99,21 +102,228 @@ func TestGrow (t *testing.T) {
 		&Data{continuousFeatures: []float64{4.0, 0.0}, output: 2.0,
 			featureSelector: func (s int32) float64 {return []float64{4.0,0.0}[s%2]}}}
 
-	factory := EntropyAccumulatorFactory(3)
+	factory := unitWeightFactory(EntropyAccumulatorFactory(3))
 	accumulator := factory()
 	for _,d := range test {
-		accumulator.Add(d.output)
+		accumulator.Add(d.output, 1.0)
 	}
 	treeNode := NewTreeNode (accumulator)
 
 //	f := continuousFeatureEntropySplitter (3)
 
-	treeNode.grow(test, 10, 1, 128, factory)
+	config := NewTree(EntropyAccumulatorFactory(3))
+	config.SetMinLeafSize(1)
+	config.SetFeaturesToTry(128)
+	treeNode.grow(test, 10, config)
 	for _,d := range test {
 		if d.output != treeNode.classify(d.featureSelector).Estimate() {
 			t.Errorf ("%g classified as %g\n", d.output, treeNode.classify(d.featureSelector))
 		}
 	}
 	
-	treeNode.dump(os.Stdout, 0, 0)
+	treeNode.dump(os.Stdout, 0, 0, nil)
+}
+
+func TestWeightedSplit (t *testing.T) {
+	// A record with weight 2 should split exactly like two copies of
+	// the record with weight 1.
+	weighted := []*Data {
+		&Data{output: 4.0, weight: 2.0, featureSelector: func (int32) float64 {return 1.0}},
+		&Data{output: 5.0, weight: 1.0, featureSelector: func (int32) float64 {return 2.0}},
+		&Data{output: 9.0, weight: 1.0, featureSelector: func (int32) float64 {return 3.0}}}
+
+	duplicated := []*Data {
+		&Data{output: 4.0, weight: 1.0, featureSelector: func (int32) float64 {return 1.0}},
+		&Data{output: 4.0, weight: 1.0, featureSelector: func (int32) float64 {return 1.0}},
+		&Data{output: 5.0, weight: 1.0, featureSelector: func (int32) float64 {return 2.0}},
+		&Data{output: 9.0, weight: 1.0, featureSelector: func (int32) float64 {return 3.0}}}
+
+	ws := continuousFeatureSplit(weighted, 0, WeightedStatAccumulatorFactory())
+	ds := continuousFeatureSplit(duplicated, 0, WeightedStatAccumulatorFactory())
+
+	if ws.splitValue != 3.0 || ds.splitValue != 3.0 {
+		t.Errorf ("expected split at 3 for both; got weighted: %v; duplicated: %v", ws.splitValue, ds.splitValue)
+	}
+	if !aboutEqual(ws.compositeSplitMetric, ds.compositeSplitMetric) {
+		t.Errorf ("weighted split metric %v does not match duplicated split metric %v", ws.compositeSplitMetric, ds.compositeSplitMetric)
+	}
+	if ws.left.WeightedCount() != 3.0 || ws.left.Count() != 2 {
+		t.Errorf ("expected left weight 3 and count 2; got %v and %v", ws.left.WeightedCount(), ws.left.Count())
+	}
+
+	// Weighted trees use the weights; unweighted trees ignore them.
+	weightedTree := NewWeightedTree(WeightedStatAccumulatorFactory())
+	weightedTree.SetMaxDepth(0)
+	weightedTree.Train(weighted)
+	if e := weightedTree.Classify(func (int32) float64 {return 0.0}).Estimate(); e != 5.5 {
+		t.Errorf ("weighted tree estimate is %v; expected 5.5", e)
+	}
+
+	tree := NewTree(StatAccumulatorFactory())
+	tree.SetMaxDepth(0)
+	tree.Train(weighted)
+	if e := tree.Classify(func (int32) float64 {return 0.0}).Estimate(); e != 6.0 {
+		t.Errorf ("unweighted tree estimate is %v; expected 6", e)
+	}
+}
+
+func TestMissingValueSplit (t *testing.T) {
+	nan := math.NaN()
+	makeData := func(missingOutput float64) []*Data {
+		data := make([]*Data, 0)
+		for _,v := range []float64{1.0, nan, 2.0, 3.0, nan, 4.0} {
+			value := v
+			output := 1.0
+			if math.IsNaN(v) {
+				output = missingOutput
+			} else if v >= 3.0 {
+				output = 9.0
+			}
+			data = append(data, &Data{output: output, outputCategories: 1, weight: 1.0,
+				featureSelector: func (int32) float64 {return value}})
+		}
+		return data
+	}
+
+	// Missing values follow the records they resemble.
+	for _,missingOutput := range []float64{1.0, 9.0} {
+		split := continuousFeatureSplit(makeData(missingOutput), 0, WeightedStatAccumulatorFactory())
+		if split.splitValue != 3.0 || !closeTo(split.compositeSplitMetric, 0.0) {
+			t.Errorf ("missing output %g: expected a perfect split at 3; got %v", missingOutput, &split)
+		}
+		if split.missingLeft != (missingOutput == 1.0) {
+			t.Errorf ("missing output %g: missing values sent left: %v", missingOutput, split.missingLeft)
+		}
+
+		tree := NewTree(StatAccumulatorFactory())
+		tree.SetRand(rand.New(rand.NewSource(1)))
+		tree.Train(makeData(missingOutput))
+		if e := tree.Classify(func (int32) float64 {return nan}).Estimate(); !closeTo(e, missingOutput) {
+			t.Errorf ("missing value classified as %g; expected %g", e, missingOutput)
+		}
+	}
+
+	// Missingness alone can be the best split.
+	data := makeData(5.0)
+	for _,d := range data {
+		if !math.IsNaN(d.featureSelector(0)) {
+			d.output = 1.0
+		}
+	}
+	split := continuousFeatureSplit(data, 0, WeightedStatAccumulatorFactory())
+	if !closeTo(split.compositeSplitMetric, 0.0) || split.missingLeft || split.left.Count() != 4 || split.splitValue <= 4.0 {
+		t.Errorf ("expected missing values alone on the right; got %v", &split)
+	}
+}
+
+func TestDumpDescriptions (t *testing.T) {
+	data := make([]*Data, 0)
+	for i:=0; i<4; i++ {
+		features := []float64{float64(i)}
+		data = append(data, &Data{
+			continuousFeatures: features,
+			categoricalFeatures: []int{i % 2},
+			output: float64(i % 2),
+			weight: 1.0,
+			featureSelector: func (int32) float64 { return features[0] }})
+	}
+
+	tree := NewTree(StatAccumulatorFactory())
+	tree.SetRand(rand.New(rand.NewSource(1)))
+	tree.SetFeaturesToTry(4)
+	tree.SetFeatureDescriber(ColumnDescriber([]string{"width"}, []string{"color"}))
+	tree.Train(data)
+
+	var b bytes.Buffer
+	tree.Dump(&b)
+	if !strings.Contains(b.String(), "Split on color") && !strings.Contains(b.String(), "Split on width") {
+		t.Errorf ("expected a described split; got:\n%s", b.String())
+	}
+	if strings.Contains(b.String(), "???") {
+		t.Errorf ("undescribed split in:\n%s", b.String())
+	}
+}
+
+// forEachNode() calls "f" on "tree" and each of its descendants.
+func forEachNode(tree *treeNode, f func(*treeNode)) {
+	f(tree)
+	if tree.seed != -1 {
+		forEachNode(tree.left, f)
+		forEachNode(tree.right, f)
+	}
+}
+
+func TestStoppingRules (t *testing.T) {
+	rng := rand.New(rand.NewSource(8))
+	data := boostingTestData(400, rng, func(x []float64) float64 {
+		return x[0]*x[1] + rng.NormFloat64()
+	})
+	newTree := func() *Tree {
+		tree := NewTree(StatAccumulatorFactory())
+		tree.SetFeaturesToTry(2)
+		tree.SetRand(rand.New(rand.NewSource(9)))
+		return tree
+	}
+	full := newTree()
+	full.Train(append([]*Data(nil), data...))
+
+	tree := newTree()
+	tree.SetMinSplitSize(50)
+	tree.Train(append([]*Data(nil), data...))
+	forEachNode(tree.root, func(node *treeNode) {
+		if node.seed != -1 && node.statistics.Count() < 50 {
+			t.Errorf ("node of %d records split", node.statistics.Count())
+		}
+	})
+	if tree.Leaves() >= full.Leaves() {
+		t.Errorf ("minimum split size leaves %d of %d leaves", tree.Leaves(), full.Leaves())
+	}
+
+	tree = newTree()
+	tree.SetMinWeightFraction(0.05)
+	tree.Train(append([]*Data(nil), data...))
+	forEachNode(tree.root, func(node *treeNode) {
+		if node.seed == -1 && node.statistics.WeightedCount() < 20.0 {
+			t.Errorf ("leaf of weight %g", node.statistics.WeightedCount())
+		}
+	})
+
+	tree = newTree()
+	tree.SetMinImpurityDecrease(0.01)
+	tree.Train(append([]*Data(nil), data...))
+	forEachNode(tree.root, func(node *treeNode) {
+		if node.seed != -1 {
+			decrease := node.statistics.WeightedCount()/400.0*
+				(node.statistics.Metric() - compositeMetric(node.left.statistics, node.right.statistics))
+			if decrease < 0.01 {
+				t.Errorf ("split decreasing the metric by %g", decrease)
+			}
+		}
+	})
+	if tree.Leaves() < 2 || tree.Leaves() >= full.Leaves()/4 {
+		t.Errorf ("minimum impurity decrease leaves %d of %d leaves", tree.Leaves(), full.Leaves())
+	}
+}
+
+func TestZeroTotalWeight (t *testing.T) {
+	data := make([]*Data, 0)
+	for i:=0; i<10; i++ {
+		features := []float64{float64(i)}
+		data = append(data, &Data{continuousFeatures: features, output: float64(i % 2), outputCategories: 2})
+	}
+	UseColumnSelectors(data)
+
+	// Unweighted trees ignore the unset weights.
+	tree := NewTree(EntropyAccumulatorFactory(2))
+	tree.Train(data)
+	if tree.Leaves() < 2 {
+		t.Errorf ("unweighted tree has %d leaves", tree.Leaves())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf ("weighted tree trained on records of zero weight")
+		}
+	}()
+	NewWeightedTree(WeightedEntropyAccumulatorFactory(2)).Train(data)
 }
@@ -0,0 +1,82 @@
+package ML
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func checkWeightedMeanAndVariance(t *testing.T, msg string, a *WeightedStatAccumulator, mean, variance float64) {
+	if !aboutEqual(a.Metric(), variance) {
+		t.Errorf ("Variance() %s is %v; expected %v", msg, a.Metric(), variance)
+	}
+
+	if !aboutEqual(a.Estimate(), mean) {
+		t.Errorf ("Mean() %s is %v; expected %v", msg, a.Estimate(), mean)
+	}
+}
+
+func TestWeightedStatAccumulator (t *testing.T) {
+	test1 := []float64 { 3.0, 6.0, 18.0}
+	weights1 := []float64 { 1.0, 2.0, 1.0}
+	var a WeightedStatAccumulator
+
+	checkWeightedMeanAndVariance(t, "after initialization", &a, 0.0, 0.0)
+
+	for i,v := range test1 {
+		a.Add(v, weights1[i])
+	}
+
+	s := fmt.Sprintf ("on data %v with weights %v", test1, weights1)
+
+	// Same as the unweighted data {3, 6, 6, 18}
+	checkWeightedMeanAndVariance(t, s, &a, 8.25, 33.1875)
+
+	if a.Count() != 3 || a.WeightedCount() != 4.0 {
+		t.Errorf ("Count() is %d and WeightedCount() is %g; expected 3 and 4", a.Count(), a.WeightedCount())
+	}
+
+	a.Remove(6.0, 2.0)
+
+	checkWeightedMeanAndVariance(t, s, &a, 10.5, 56.25)
+
+	a.Remove(3.0, 1.0)
+	a.Remove(18.0, 1.0)
+
+	checkWeightedMeanAndVariance(t, s, &a, 0.0, 0.0)
+}
+
+func TestWeightedEntropyAccumulator (t *testing.T) {
+	a := NewWeightedEntropyAccumulator(3)
+
+	a.Add(0.0, 0.5)
+	a.Add(1.0, 0.25)
+	a.Add(1.0, 0.25)
+	a.Add(2.0, 0.75)
+
+	if a.Count() != 4 || a.WeightedCount() != 1.75 {
+		t.Errorf ("Count() is %d and WeightedCount() is %g; expected 4 and 1.75", a.Count(), a.WeightedCount())
+	}
+	if a.Estimate() != 2.0 {
+		t.Errorf ("Estimate() is %g; expected 2", a.Estimate())
+	}
+
+	a.Remove(2.0, 0.75)
+	// Categories 0 and 1 now have equal weight, so entropy is one bit.
+	if !aboutEqual(a.Metric(), 1.0) {
+		t.Errorf ("Metric() is %g; expected 1", a.Metric())
+	}
+
+	c := a.Clone()
+	a.Clear()
+	if c.Count() != 3 || c.WeightedCount() != 1.0 {
+		t.Errorf ("Clone() was modified by Clear() on the original: %v", c)
+	}
+
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "category 3") {
+			t.Errorf ("Remove() of an out of range category panicked with %v", r)
+		}
+	}()
+	c.(*WeightedEntropyAccumulator).Remove(3.0, 1.0)
+}
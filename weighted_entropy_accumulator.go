@@ -0,0 +1,153 @@
+package ML
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// WeightedEntropyAccumulator accumulates the total weight of each
+// output category.  It is the weighted analog of EntropyAccumulator.
+type WeightedEntropyAccumulator struct {
+	weights []float64
+	totalCount int
+	totalWeight float64
+}
+
+func (ea WeightedEntropyAccumulator) String() string {
+	s := fmt.Sprintf ("weightedentropyaccumulator[%d/%g]: ", ea.totalCount, ea.totalWeight)
+	for i,w := range ea.weights {
+		s = s + fmt.Sprintf(" %d:%g", i, w)
+	}
+	return s
+}
+
+func NewWeightedEntropyAccumulator(categoryValueCount int) *WeightedEntropyAccumulator {
+	return &WeightedEntropyAccumulator{
+		weights: make([]float64, categoryValueCount),
+		totalCount: 0,
+		totalWeight: 0.0}
+}
+
+func WeightedEntropyAccumulatorFactory (categoryValueCount int) func() WeightedCVAccumulator {
+	return func() WeightedCVAccumulator {
+		return NewWeightedEntropyAccumulator (categoryValueCount)
+	}
+}
+
+func (ea *WeightedEntropyAccumulator) Clone() WeightedErrorAccumulator {
+	weights := make([]float64, len(ea.weights))
+	copy(weights,ea.weights)
+	return &WeightedEntropyAccumulator{
+		weights: weights,
+		totalCount: ea.totalCount,
+		totalWeight: ea.totalWeight}
+}
+
+func (ea *WeightedEntropyAccumulator) Add(category, weight float64) {
+	if int(category) >= len(ea.weights) || (int(category) < 0) {
+		panic (fmt.Sprintf ("Attempt to add to category %g but only %d categories", category, len(ea.weights)))
+	}
+	ea.totalCount += 1
+	ea.totalWeight += weight
+	ea.weights[int(category)] += weight
+}
+
+func (ea *WeightedEntropyAccumulator) Remove(category, weight float64) {
+	if int(category) >= len(ea.weights) || (int(category) < 0) {
+		panic (fmt.Sprintf ("Attempt to remove from category %g but only %d categories", category, len(ea.weights)))
+	}
+	if ea.totalCount == 0 {
+		panic(errors.New(fmt.Sprintf("More calls to Remove() than to Add() for category %v", int(category))))
+	}
+	ea.totalCount -= 1
+	if ea.totalCount == 0 {
+		// Avoid leaving rounding residue behind in an empty accumulator.
+		ea.Clear()
+		return
+	}
+	ea.totalWeight -= weight
+	ea.weights[int(category)] -= weight
+}
+
+// Merge() adds the category weights of "other", which must be a
+// *WeightedEntropyAccumulator with the same number of categories.
+func (ea *WeightedEntropyAccumulator) Merge(other WeightedErrorAccumulator) {
+	o,ok := other.(*WeightedEntropyAccumulator)
+	if !ok {
+		panic(errors.New(fmt.Sprintf("Cannot merge %T into a WeightedEntropyAccumulator", other)))
+	}
+	if len(o.weights) != len(ea.weights) {
+		panic(errors.New(fmt.Sprintf("Cannot merge %d categories into %d", len(o.weights), len(ea.weights))))
+	}
+	for i,w := range o.weights {
+		ea.weights[i] += w
+	}
+	ea.totalCount += o.totalCount
+	ea.totalWeight += o.totalWeight
+}
+
+func (ea *WeightedEntropyAccumulator) Count() int {
+	return ea.totalCount
+}
+
+func (ea *WeightedEntropyAccumulator) WeightedCount() float64 {
+	return ea.totalWeight
+}
+
+// Estimate() returns the category with the largest total weight.
+func (ea *WeightedEntropyAccumulator) Estimate() float64 {
+	maxWeight := 0.0
+	result := 0.0
+	for i,w := range ea.weights {
+		if w > maxWeight {
+			maxWeight = w
+			result = float64(i)
+		}
+	}
+	return result
+}
+
+func (ea *WeightedEntropyAccumulator) Metric() float64 {
+	entropy := 0.0
+	if ea.totalWeight <= 0.0 {
+		return entropy
+	}
+	for _,w := range ea.weights {
+		if w > 0.0 {
+			p := w/ea.totalWeight
+			entropy -= p * math.Log2(p)
+		}
+	}
+	return entropy
+}
+
+func (ea *WeightedEntropyAccumulator) Clear() {
+	for i,_ := range ea.weights {
+		ea.weights[i] = 0.0
+	}
+	ea.totalCount = 0
+	ea.totalWeight = 0.0
+}
+
+func (ea *WeightedEntropyAccumulator) Dump(w io.Writer, indent int) {
+	fmt.Fprintf (w, "%*scount: %d weight: %g ", indent, "", ea.totalCount, ea.totalWeight)
+	for i,c := range ea.weights {
+		fmt.Fprintf (w, "  %d:%g", i, c)
+	}
+	fmt.Fprintf(w, "\n")
+}
+
+// Probability() returns the fraction of the total weight in "category".
+func (ea *WeightedEntropyAccumulator) Probability(category float64) float64 {
+	if ea.totalWeight <= 0.0 {
+		return 0.0
+	}
+	return ea.weights[int(category)]/ea.totalWeight
+}
+
+func (ea *WeightedEntropyAccumulator) FrequencyEstimate(value float64) float64 {
+	weight := ea.weights[int(value)]
+	return (weight + 1.0)/(ea.totalWeight + float64(len(ea.weights)))
+}
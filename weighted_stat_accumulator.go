@@ -0,0 +1,124 @@
+package ML
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// WeightedStatAccumulator accumulates the weighted mean and variance
+// of its inputs.  Like StatAccumulator, it keeps the mean and the
+// weighted sum of squared deviations from the mean, updated with
+// West's weighted form of Welford's method.  A new
+// WeightedStatAccumulator may be declared without initialization.
+// The Go default initialization is correct.
+type WeightedStatAccumulator struct {
+	count int
+	weightedCount float64
+	mean float64
+	// m2 is the weighted sum of squared deviations from the mean.
+	m2 float64
+}
+
+func WeightedStatAccumulatorFactory() func() WeightedCVAccumulator {
+	return func () WeightedCVAccumulator {
+		return &WeightedStatAccumulator{}
+	}
+}
+
+func (sa *WeightedStatAccumulator) Clone() WeightedErrorAccumulator {
+	return &WeightedStatAccumulator{
+		count: sa.count,
+		weightedCount: sa.weightedCount,
+		mean: sa.mean,
+		m2: sa.m2}
+}
+
+func (sa *WeightedStatAccumulator) Add(x, weight float64) {
+	sa.count += 1
+	sa.weightedCount += weight
+	if sa.weightedCount <= 0.0 {
+		return
+	}
+	delta := x - sa.mean
+	sa.mean += delta*weight/sa.weightedCount
+	sa.m2 += weight*delta*(x - sa.mean)
+}
+
+// Remove() reverses Add(x, weight).
+func (sa *WeightedStatAccumulator) Remove(x, weight float64) {
+	if sa.count == 0 {
+		panic(errors.New("More calls to Remove() than to Add()"))
+	}
+	sa.count -= 1
+	if sa.count == 0 {
+		// Avoid leaving rounding residue behind in an empty accumulator.
+		sa.Clear()
+		return
+	}
+	sa.weightedCount -= weight
+	if sa.weightedCount <= 0.0 {
+		// Only records of zero weight remain.
+		sa.weightedCount = 0.0
+		sa.mean = 0.0
+		sa.m2 = 0.0
+		return
+	}
+	previousMean := sa.mean - weight*(x - sa.mean)/sa.weightedCount
+	sa.m2 -= weight*(x - previousMean)*(x - sa.mean)
+	sa.mean = previousMean
+	if sa.m2 < 0.0 {
+		sa.m2 = 0.0
+	}
+}
+
+// Merge() adds the inputs accumulated by "other", which must be a
+// *WeightedStatAccumulator, as if they had been added to "sa".
+func (sa *WeightedStatAccumulator) Merge(other WeightedErrorAccumulator) {
+	o,ok := other.(*WeightedStatAccumulator)
+	if !ok {
+		panic(errors.New(fmt.Sprintf("Cannot merge %T into a WeightedStatAccumulator", other)))
+	}
+	sa.count += o.count
+	weightedCount := sa.weightedCount + o.weightedCount
+	if weightedCount <= 0.0 {
+		sa.weightedCount = weightedCount
+		return
+	}
+	delta := o.mean - sa.mean
+	sa.mean += delta*o.weightedCount/weightedCount
+	sa.m2 += o.m2 + delta*delta*sa.weightedCount*o.weightedCount/weightedCount
+	sa.weightedCount = weightedCount
+}
+
+func (sa *WeightedStatAccumulator) Count() int {
+	return sa.count
+}
+
+func (sa *WeightedStatAccumulator) WeightedCount() float64 {
+	return sa.weightedCount
+}
+
+// Metric() returns the weighted variance.
+func (sa *WeightedStatAccumulator) Metric() float64 {
+	if sa.weightedCount <= 0.0 {
+		return 0.0
+	}
+	return sa.m2/sa.weightedCount
+}
+
+// Estimate() returns the weighted mean.
+func (sa *WeightedStatAccumulator) Estimate() float64 {
+	return sa.mean
+}
+
+func (sa *WeightedStatAccumulator) Clear() {
+	sa.count = 0
+	sa.weightedCount = 0.0
+	sa.mean = 0.0
+	sa.m2 = 0.0
+}
+
+func (sa *WeightedStatAccumulator) Dump(w io.Writer, indent int) {
+	fmt.Fprintf (w, "%*scount: %d, sum(w): %g, mean: %g, sum(w(x-mean)^2): %g\n", indent, "", sa.count, sa.weightedCount, sa.mean, sa.m2)
+}